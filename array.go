@@ -0,0 +1,198 @@
+package firebolt
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LambdaExpr is a lambda function of array functions, e.g. x -> x > 5
+type LambdaExpr struct {
+	Params []string
+	SQL    string
+	Vars   []interface{}
+}
+
+// Lambda returns a lambda function with a single parameter, the body binds vars like Where does, e.g.
+//
+//	firebolt.Lambda("x", "x > ?", 5)
+func Lambda(param string, sql string, vars ...interface{}) LambdaExpr {
+	return LambdaExpr{Params: []string{param}, SQL: sql, Vars: vars}
+}
+
+func (lambda LambdaExpr) Build(builder clause.Builder) {
+	if len(lambda.Params) == 1 {
+		builder.WriteString(lambda.Params[0])
+	} else {
+		builder.WriteString("(" + strings.Join(lambda.Params, ", ") + ")")
+	}
+	builder.WriteString(" -> ")
+	clause.Expr{SQL: lambda.SQL, Vars: lambda.Vars}.Build(builder)
+}
+
+// ArrayFunc is a call of an array function, it can be used as a predicate in Where, Not and Or,
+// or compared with a value
+type ArrayFunc struct {
+	Name string
+	// Args are the function arguments, columns are quoted and other values are bound as vars
+	Args []interface{}
+}
+
+func (f ArrayFunc) Build(builder clause.Builder) {
+	builder.WriteString(f.Name)
+	builder.WriteByte('(')
+	for idx, arg := range f.Args {
+		if idx > 0 {
+			builder.WriteString(", ")
+		}
+		builder.AddVar(builder, arg)
+	}
+	builder.WriteByte(')')
+}
+
+// Eq returns the condition of the function result being equal to value
+func (f ArrayFunc) Eq(value interface{}) clause.Expression {
+	return clause.Expr{SQL: "? = ?", Vars: []interface{}{f, value}}
+}
+
+// Gt returns the condition of the function result being greater than value
+func (f ArrayFunc) Gt(value interface{}) clause.Expression {
+	return clause.Expr{SQL: "? > ?", Vars: []interface{}{f, value}}
+}
+
+// Gte returns the condition of the function result being greater than or equal to value
+func (f ArrayFunc) Gte(value interface{}) clause.Expression {
+	return clause.Expr{SQL: "? >= ?", Vars: []interface{}{f, value}}
+}
+
+// Lt returns the condition of the function result being less than value
+func (f ArrayFunc) Lt(value interface{}) clause.Expression {
+	return clause.Expr{SQL: "? < ?", Vars: []interface{}{f, value}}
+}
+
+// Lte returns the condition of the function result being less than or equal to value
+func (f ArrayFunc) Lte(value interface{}) clause.Expression {
+	return clause.Expr{SQL: "? <= ?", Vars: []interface{}{f, value}}
+}
+
+// ArrayContains returns CONTAINS condition, which is true when the array column contains value
+func ArrayContains(column string, value interface{}) ArrayFunc {
+	return ArrayFunc{Name: "CONTAINS", Args: []interface{}{columnOf(column), value}}
+}
+
+// ArrayAny returns ANY_MATCH condition, which is true when the lambda is true for any element of the array column
+func ArrayAny(column string, lambda LambdaExpr) ArrayFunc {
+	return ArrayFunc{Name: "ANY_MATCH", Args: []interface{}{lambda, columnOf(column)}}
+}
+
+// ArrayAll returns ALL_MATCH condition, which is true when the lambda is true for all the elements of the array column
+func ArrayAll(column string, lambda LambdaExpr) ArrayFunc {
+	return ArrayFunc{Name: "ALL_MATCH", Args: []interface{}{lambda, columnOf(column)}}
+}
+
+// ArrayCount returns ARRAY_COUNT, the number of elements of the array column the lambda is true for
+func ArrayCount(column string, lambda LambdaExpr) ArrayFunc {
+	return ArrayFunc{Name: "ARRAY_COUNT", Args: []interface{}{lambda, columnOf(column)}}
+}
+
+// ArrayLength returns LENGTH of the array column
+func ArrayLength(column string) ArrayFunc {
+	return ArrayFunc{Name: "LENGTH", Args: []interface{}{columnOf(column)}}
+}
+
+// Transform returns TRANSFORM, the array column with the lambda applied to each element
+func Transform(column string, lambda LambdaExpr) ArrayFunc {
+	return ArrayFunc{Name: "TRANSFORM", Args: []interface{}{lambda, columnOf(column)}}
+}
+
+// Array is a value of an ARRAY column, e.g. Array[string] for ARRAY(TEXT) and Array[[]int32] for ARRAY(ARRAY(INT)).
+// It is scanned from the []driver.Value arrays returned by the SDK and written as an array literal,
+// a nil Array is written as NULL
+type Array[T any] []T
+
+func (a *Array[T]) Scan(src interface{}) error {
+	if src == nil {
+		*a = nil
+		return nil
+	}
+	return scanArray(reflect.ValueOf(a).Elem(), src)
+}
+
+func (a Array[T]) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	if a == nil {
+		return clause.Expr{SQL: "NULL"}
+	}
+	return arrayLiteral(reflect.ValueOf(a))
+}
+
+// arrayLiteral returns the literal of the slice with its elements bound as vars, e.g. [?,?]
+func arrayLiteral(slice reflect.Value) clause.Expr {
+	expr := clause.Expr{SQL: "[", Vars: make([]interface{}, 0, slice.Len())}
+	for idx := 0; idx < slice.Len(); idx++ {
+		if idx > 0 {
+			expr.SQL += ","
+		}
+		expr.SQL += "?"
+		if elem := slice.Index(idx); isArrayType(elem.Type()) {
+			expr.Vars = append(expr.Vars, arrayLiteral(elem))
+		} else {
+			expr.Vars = append(expr.Vars, elem.Interface())
+		}
+	}
+	expr.SQL += "]"
+	return expr
+}
+
+// scanArray sets dst, a slice, to the elements of src converted to its element type, NULL elements are left zero
+func scanArray(dst reflect.Value, src interface{}) error {
+	values := reflect.ValueOf(src)
+	if values.Kind() != reflect.Slice || !isArrayType(values.Type()) {
+		return fmt.Errorf("can't scan %T into %s", src, dst.Type())
+	}
+	slice := reflect.MakeSlice(dst.Type(), values.Len(), values.Len())
+	for idx := 0; idx < values.Len(); idx++ {
+		if err := scanElem(slice.Index(idx), values.Index(idx).Interface()); err != nil {
+			return fmt.Errorf("array element %d: %w", idx, err)
+		}
+	}
+	dst.Set(slice)
+	return nil
+}
+
+func scanElem(dst reflect.Value, src interface{}) error {
+	if src == nil {
+		return nil
+	}
+	if scanner, ok := dst.Addr().Interface().(sql.Scanner); ok {
+		return scanner.Scan(src)
+	}
+	value := reflect.ValueOf(src)
+	switch {
+	case dst.Kind() == reflect.Ptr:
+		elem := reflect.New(dst.Type().Elem())
+		if err := scanElem(elem.Elem(), src); err != nil {
+			return err
+		}
+		dst.Set(elem)
+	case isArrayType(dst.Type()):
+		return scanArray(dst, src)
+	case value.Type().AssignableTo(dst.Type()):
+		dst.Set(value)
+	// numbers convert to strings as runes otherwise
+	case value.Type().ConvertibleTo(dst.Type()) && (dst.Kind() == reflect.String) == (value.Kind() == reflect.String):
+		dst.Set(value.Convert(dst.Type()))
+	default:
+		return fmt.Errorf("can't scan %T into %s", src, dst.Type())
+	}
+	return nil
+}
+
+// isArrayType tells whether t is mapped to ARRAY, []byte is BYTEA
+func isArrayType(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8
+}
@@ -0,0 +1,100 @@
+package firebolt
+
+import (
+	"strings"
+	"testing"
+
+	fireboltgosdk "github.com/firebolt-db/firebolt-go-sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArrayPredicates(t *testing.T) {
+	db := dryRunDB(t)
+
+	stmt := db.Where(ArrayContains("tags", "go")).
+		Where(ArrayAny("scores", Lambda("x", "x > ?", 90))).
+		Find(&[]testPost{}).Statement
+	assert.Equal(t,
+		`SELECT * FROM "test_posts" WHERE CONTAINS("tags", ?) AND ANY_MATCH(x -> x > ?, "scores")`,
+		stmt.SQL.String())
+	assert.Equal(t, []interface{}{"go", 90}, stmt.Vars)
+
+	stmt = db.Not(ArrayContains("tags", "draft")).
+		Or(ArrayAll("scores", Lambda("s", "s BETWEEN ? AND ?", 1, 10))).
+		Find(&[]testPost{}).Statement
+	assert.Equal(t,
+		`SELECT * FROM "test_posts" WHERE NOT CONTAINS("tags", ?) OR ALL_MATCH(s -> s BETWEEN ? AND ?, "scores")`,
+		stmt.SQL.String())
+	assert.Equal(t, []interface{}{"draft", 1, 10}, stmt.Vars)
+}
+
+func TestArrayFunctionComparisons(t *testing.T) {
+	db := dryRunDB(t)
+
+	stmt := db.Where(ArrayLength("tags").Gt(2)).
+		Where(ArrayCount("test_posts.scores", Lambda("x", "x >= ?", 50)).Lte(3)).
+		Select("id, ?", Transform("scores", Lambda("x", "x * ?", 2))).
+		Find(&[]testPost{}).Statement
+	assert.Equal(t,
+		`SELECT id, TRANSFORM(x -> x * ?, "scores") FROM "test_posts" WHERE LENGTH("tags") > ? AND ARRAY_COUNT(x -> x >= ?, "test_posts"."scores") <= ?`,
+		stmt.SQL.String())
+	assert.Equal(t, []interface{}{2, 2, 50, 3}, stmt.Vars)
+}
+
+func TestLambdaParams(t *testing.T) {
+	sql, vars := buildExpr(t, LambdaExpr{Params: []string{"x", "y"}, SQL: "x + y > ?", Vars: []interface{}{1}})
+	assert.Equal(t, `(x, y) -> x + y > ?`, sql)
+	assert.Equal(t, []interface{}{1}, vars)
+}
+
+type testArrayRow struct {
+	ID     int64
+	Tags   Array[string]      `gorm:"type:ARRAY(TEXT)"`
+	Matrix Array[[]int32]     `gorm:"type:ARRAY(ARRAY(INT))"`
+	Scores Array[*int64]      `gorm:"type:ARRAY(LONG NULL)"`
+	Labels Array[interface{}] `gorm:"type:ARRAY(TEXT)"`
+}
+
+func TestArraySDK(t *testing.T) {
+	server := newFakeFirebolt(t, func(query string) (*fireboltgosdk.QueryResponse, error) {
+		if !strings.HasPrefix(query, "SELECT") {
+			return &fireboltgosdk.QueryResponse{}, nil
+		}
+		return &fireboltgosdk.QueryResponse{
+			Meta: []fireboltgosdk.Column{
+				{Name: "id", Type: "long"}, {Name: "tags", Type: "array(text)"}, {Name: "matrix", Type: "array(array(int))"},
+				{Name: "scores", Type: "array(long null)"}, {Name: "labels", Type: "array(text)"},
+			},
+			Data: [][]interface{}{
+				{1, []interface{}{"a", "b"}, []interface{}{[]interface{}{1, 2}, []interface{}{}}, []interface{}{10, nil}, []interface{}{"x"}},
+				{2, nil, []interface{}{}, nil, nil},
+			},
+			Rows: 2,
+		}, nil
+	})
+	db := openSDKDB(t, Config{}, server)
+
+	var rows []testArrayRow
+	assert.NoError(t, db.Find(&rows).Error)
+	score := int64(10)
+	assert.Equal(t, []testArrayRow{
+		{ID: 1, Tags: Array[string]{"a", "b"}, Matrix: Array[[]int32]{{1, 2}, {}}, Scores: Array[*int64]{&score, nil}, Labels: Array[interface{}]{"x"}},
+		{ID: 2, Matrix: Array[[]int32]{}},
+	}, rows)
+
+	assert.NoError(t, db.Create(&rows).Error)
+	requests := server.received()
+	assert.Equal(t,
+		`INSERT INTO "test_array_rows" ("tags","matrix","scores","labels","id") VALUES `+
+			`(['a','b'],[[1,2],[]],[10,NULL],['x'],1),(NULL,[],NULL,NULL,2)`,
+		requests[len(requests)-1].query)
+}
+
+func TestArrayScanErrors(t *testing.T) {
+	var tags Array[int32]
+	assert.EqualError(t, tags.Scan("[1]"), "can't scan string into firebolt.Array[int32]")
+	assert.EqualError(t, tags.Scan([]interface{}{int32(1), "2"}), "array element 1: can't scan string into int32")
+
+	var names Array[string]
+	assert.EqualError(t, names.Scan([]interface{}{int32(1)}), "array element 0: can't scan int32 into string")
+}
@@ -0,0 +1,243 @@
+package firebolt
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AsyncState is the state of an asynchronous query
+type AsyncState string
+
+const (
+	AsyncRunning   AsyncState = "RUNNING"
+	AsyncSucceeded AsyncState = "ENDED_SUCCESSFULLY"
+	AsyncFailed    AsyncState = "FAILED"
+	AsyncCanceled  AsyncState = "CANCELLED"
+)
+
+// Done tells whether the query has finished
+func (state AsyncState) Done() bool {
+	return state == AsyncSucceeded || state == AsyncFailed || state == AsyncCanceled
+}
+
+// DefaultAsyncPollInterval is the interval Wait checks the status of an asynchronous query with
+var DefaultAsyncPollInterval = time.Second
+
+// AsyncStatus is the status of an asynchronous query returned by fb_GetAsyncStatus
+type AsyncStatus struct {
+	State   AsyncState
+	QueryID string
+	// Error is the error message of a failed query
+	Error string
+}
+
+// AsyncQueryError is returned by Wait when an asynchronous query failed or was cancelled
+type AsyncQueryError struct {
+	Token  string
+	Status AsyncStatus
+}
+
+func (e *AsyncQueryError) Error() string {
+	if e.Status.State == AsyncCanceled {
+		return fmt.Sprintf("async query %s was cancelled", e.Token)
+	}
+	return fmt.Sprintf("async query %s failed: %s", e.Token, e.Status.Error)
+}
+
+// ErrAsyncUnsupported is returned by AsyncDB.Exec when the connections of the statement don't apply async_execution setting,
+// e.g. a connection pool set with Dialector.Conn, the statement would run synchronously otherwise
+var ErrAsyncUnsupported = errors.New("firebolt: async queries require connections opened from DSN")
+
+type asyncExecutionKey struct{}
+
+func isAsyncExecution(ctx context.Context) bool {
+	return ctx.Value(asyncExecutionKey{}) != nil
+}
+
+// AsyncDB submits statements for asynchronous execution, the engine runs them in the background
+// and the connection is released as soon as the statement is accepted
+type AsyncDB struct {
+	db *gorm.DB
+}
+
+// Async returns AsyncDB submitting statements with the session of db, e.g.
+//
+//	query, err := firebolt.Async(db).Exec("INSERT INTO daily_totals SELECT day, sum(amount) FROM orders GROUP BY day")
+//	status, err := query.Wait(ctx)
+//
+// Statements are submitted with async_execution setting by connections opened from DSN, Exec returns ErrAsyncUnsupported
+// for other connections. The token is read from the token column of the row the engine answers with
+func Async(db *gorm.DB) *AsyncDB {
+	return &AsyncDB{db: db}
+}
+
+// Exec submits the statement and returns the handle of the asynchronous query
+func (async *AsyncDB) Exec(sql string, values ...interface{}) (*AsyncQuery, error) {
+	tx := async.db.Session(&gorm.Session{NewDB: true})
+	tx = tx.WithContext(context.WithValue(tx.Statement.Context, asyncExecutionKey{}, true))
+
+	var token string
+	tx = tx.Raw(sql, values...)
+	if err := scanColumns(tx, map[string]interface{}{"token": &token}); err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return nil, errors.New("async query wasn't accepted, no token returned")
+	}
+	query := &AsyncQuery{Token: token, PollInterval: DefaultAsyncPollInterval, db: async.db}
+	if engine, ok := tx.Statement.Settings.Load(engineClauseName); ok {
+		query.Engine = engine.(string)
+	}
+	return query, nil
+}
+
+// checkAsyncExecution fails async statements before they are sent to connections which run them synchronously
+func checkAsyncExecution(db *gorm.DB) {
+	if db.Error != nil || db.DryRun || !isAsyncExecution(db.Statement.Context) {
+		return
+	}
+	if ok, err := appliesSettings(db.Statement.Context, db.Statement.ConnPool); err != nil {
+		_ = db.AddError(err)
+	} else if !ok {
+		_ = db.AddError(ErrAsyncUnsupported)
+	}
+}
+
+// appliesSettings tells whether the connections of the pool apply the settings of the statement context, as sessionConn does
+func appliesSettings(ctx context.Context, pool gorm.ConnPool) (bool, error) {
+	var conn *sql.Conn
+	switch pool := pool.(type) {
+	case *sql.DB:
+		var err error
+		if conn, err = pool.Conn(ctx); err != nil {
+			return false, err
+		}
+		defer conn.Close()
+	case *sql.Conn:
+		conn = pool
+	default:
+		return false, nil
+	}
+	var ok bool
+	err := conn.Raw(func(driverConn interface{}) error {
+		_, ok = driverConn.(*sessionConn)
+		return nil
+	})
+	return ok, err
+}
+
+// AsyncQuery is a handle of an asynchronous query
+type AsyncQuery struct {
+	Token string
+	// Engine is the engine the query was submitted to when Config.Engines are set, Status and Cancel are sent to it
+	// since the token is only known to that engine
+	Engine string
+	// PollInterval is the interval Wait checks the status with
+	PollInterval time.Duration
+
+	db *gorm.DB
+}
+
+// Status returns the current status of the query
+func (query *AsyncQuery) Status(ctx context.Context) (AsyncStatus, error) {
+	var (
+		status       AsyncStatus
+		state        string
+		queryID      sql.NullString
+		errorMessage sql.NullString
+	)
+	tx := query.session(ctx).Raw("CALL fb_GetAsyncStatus(?)", query.Token)
+	err := scanColumns(tx, map[string]interface{}{"status": &state, "query_id": &queryID, "error_message": &errorMessage})
+	if err != nil {
+		return status, err
+	}
+	status.State, status.QueryID, status.Error = AsyncState(state), queryID.String, errorMessage.String
+	return status, nil
+}
+
+// Wait polls the status until the query finishes, it returns *AsyncQueryError when the query failed or was cancelled,
+// and the last known status when ctx is done first
+func (query *AsyncQuery) Wait(ctx context.Context) (AsyncStatus, error) {
+	interval := query.PollInterval
+	if interval <= 0 {
+		interval = DefaultAsyncPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last AsyncStatus
+	for {
+		status, err := query.Status(ctx)
+		if err != nil {
+			return last, err
+		}
+		last = status
+		switch status.State {
+		case AsyncSucceeded:
+			return status, nil
+		case AsyncFailed, AsyncCanceled:
+			return status, &AsyncQueryError{Token: query.Token, Status: status}
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Cancel cancels the query on the engine, a finished query is left as is
+func (query *AsyncQuery) Cancel(ctx context.Context) error {
+	status, err := query.Status(ctx)
+	if err != nil || status.State.Done() {
+		return err
+	}
+	if status.QueryID == "" {
+		return fmt.Errorf("async query %s has no query id yet", query.Token)
+	}
+	return query.session(ctx).Exec("CANCEL QUERY WHERE query_id = ?", status.QueryID).Error
+}
+
+// session returns a new session sending the statements to the engine of the query
+func (query *AsyncQuery) session(ctx context.Context) *gorm.DB {
+	tx := query.db.Session(&gorm.Session{NewDB: true}).WithContext(ctx)
+	if query.Engine != "" {
+		tx = tx.Clauses(UseEngine(query.Engine))
+	}
+	return tx
+}
+
+// scanColumns scans the first row of the query into dests by column name, other columns are skipped
+func scanColumns(tx *gorm.DB, dests map[string]interface{}) error {
+	rows, err := tx.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		return gorm.ErrRecordNotFound
+	}
+	values := make([]interface{}, len(columns))
+	for idx, column := range columns {
+		if dest, ok := dests[column]; ok {
+			values[idx] = dest
+		} else {
+			values[idx] = new(interface{})
+		}
+	}
+	return rows.Scan(values...)
+}
@@ -0,0 +1,219 @@
+package firebolt
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	fireboltgosdk "github.com/firebolt-db/firebolt-go-sdk"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// fakeAsyncServer answers async queries, status calls and cancellations with the state set by the test
+type fakeAsyncServer struct {
+	mu    sync.Mutex
+	state AsyncState
+	err   string
+}
+
+func (s *fakeAsyncServer) setState(state AsyncState, err string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state, s.err = state, err
+}
+
+func (s *fakeAsyncServer) handle(query string, args []driver.NamedValue) (*fakeResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch query {
+	case "INSERT INTO daily_totals SELECT day, sum(amount) FROM test_orders WHERE amount > ? GROUP BY day":
+		s.state = AsyncRunning
+		return &fakeResult{columns: []string{"message", "token"}, rows: [][]driver.Value{{"accepted", "token-1"}}}, nil
+	case "CALL fb_GetAsyncStatus(?)":
+		if args[0].Value != "token-1" {
+			return nil, errors.New("unknown token")
+		}
+		return &fakeResult{
+			columns: []string{"status", "query_id", "error_message"},
+			rows:    [][]driver.Value{{string(s.state), "query-1", s.err}},
+		}, nil
+	case "CANCEL QUERY WHERE query_id = ?":
+		s.state = AsyncCanceled
+	}
+	return nil, nil
+}
+
+func openAsyncDB(t *testing.T) (*gorm.DB, *fakeDriver, *fakeAsyncServer) {
+	server := &fakeAsyncServer{}
+	fake := &fakeDriver{handler: server.handle}
+	pool := sql.OpenDB(sessionConnector{Connector: fake})
+	pool.SetMaxOpenConns(1)
+	return openTestDB(t, Config{}, pool, &gorm.Config{}), fake, server
+}
+
+func submitAsync(t *testing.T, db *gorm.DB) *AsyncQuery {
+	query, err := Async(db).Exec("INSERT INTO daily_totals SELECT day, sum(amount) FROM test_orders WHERE amount > ? GROUP BY day", 0)
+	assert.NoError(t, err)
+	query.PollInterval = time.Millisecond
+	return query
+}
+
+func TestAsyncSucceeded(t *testing.T) {
+	db, fake, server := openAsyncDB(t)
+	query := submitAsync(t, db)
+	assert.Equal(t, "token-1", query.Token)
+
+	status, err := query.Status(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, AsyncStatus{State: AsyncRunning, QueryID: "query-1"}, status)
+	assert.False(t, status.State.Done())
+
+	time.AfterFunc(5*time.Millisecond, func() { server.setState(AsyncSucceeded, "") })
+	status, err = query.Wait(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, AsyncSucceeded, status.State)
+
+	queries := fake.received()
+	assert.Equal(t, []string{
+		"SET async_execution=1",
+		"INSERT INTO daily_totals SELECT day, sum(amount) FROM test_orders WHERE amount > ? GROUP BY day",
+		"SET async_execution=0",
+		"CALL fb_GetAsyncStatus(?)",
+	}, queries[:4])
+}
+
+func TestAsyncFailed(t *testing.T) {
+	db, _, server := openAsyncDB(t)
+	query := submitAsync(t, db)
+
+	server.setState(AsyncFailed, "Memory limit exceeded")
+	status, err := query.Wait(context.Background())
+	var asyncErr *AsyncQueryError
+	if assert.ErrorAs(t, err, &asyncErr) {
+		assert.Equal(t, "token-1", asyncErr.Token)
+		assert.Equal(t, AsyncFailed, asyncErr.Status.State)
+	}
+	assert.EqualError(t, err, "async query token-1 failed: Memory limit exceeded")
+	assert.True(t, status.State.Done())
+}
+
+func TestAsyncCanceled(t *testing.T) {
+	db, fake, _ := openAsyncDB(t)
+	query := submitAsync(t, db)
+
+	assert.NoError(t, query.Cancel(context.Background()))
+	assert.Contains(t, fake.received(), "CANCEL QUERY WHERE query_id = ?")
+
+	_, err := query.Wait(context.Background())
+	assert.EqualError(t, err, "async query token-1 was cancelled")
+
+	// cancelling a finished query doesn't send CANCEL QUERY again
+	before := len(fake.received())
+	assert.NoError(t, query.Cancel(context.Background()))
+	assert.Len(t, fake.received(), before+1)
+}
+
+func TestAsyncWaitTimeout(t *testing.T) {
+	db, _, _ := openAsyncDB(t)
+	query := submitAsync(t, db)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	status, err := query.Wait(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, AsyncRunning, status.State)
+}
+
+func TestAsyncNotAccepted(t *testing.T) {
+	db, _, _ := openAsyncDB(t)
+	_, err := Async(db).Exec("INSERT INTO daily_totals VALUES (1)")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestAsyncUnsupportedConn(t *testing.T) {
+	server := &fakeAsyncServer{}
+	fake := &fakeDriver{handler: server.handle}
+	db := openTestDB(t, Config{}, sql.OpenDB(fake), &gorm.Config{})
+
+	_, err := Async(db).Exec("INSERT INTO daily_totals SELECT day, sum(amount) FROM test_orders WHERE amount > ? GROUP BY day", 0)
+	assert.ErrorIs(t, err, ErrAsyncUnsupported)
+	assert.Empty(t, fake.received())
+}
+
+func TestAsyncEngine(t *testing.T) {
+	engines := map[string]*fakeDriver{}
+	pools := map[string]*sql.DB{}
+	for _, name := range []string{PrimaryEngine, "ingest1", "ingest2"} {
+		engines[name] = &fakeDriver{handler: (&fakeAsyncServer{}).handle}
+		pools[name] = sql.OpenDB(sessionConnector{Connector: engines[name]})
+	}
+	db := openTestDB(t, Config{Engines: []Engine{
+		{Name: "ingest1", Role: WriteEngine, Conn: pools["ingest1"]},
+		{Name: "ingest2", Role: WriteEngine, Conn: pools["ingest2"]},
+	}}, pools[PrimaryEngine], &gorm.Config{})
+
+	// the write engines are chosen in turn, the status calls and the cancellation follow the query
+	for _, engine := range []string{PrimaryEngine, "ingest1", "ingest2"} {
+		query := submitAsync(t, db)
+		assert.Equal(t, engine, query.Engine)
+		assert.NoError(t, query.Cancel(context.Background()))
+		_, err := query.Wait(context.Background())
+		assert.EqualError(t, err, "async query token-1 was cancelled")
+	}
+	for name, engine := range engines {
+		assert.Equal(t, []string{
+			"SET async_execution=1",
+			"INSERT INTO daily_totals SELECT day, sum(amount) FROM test_orders WHERE amount > ? GROUP BY day",
+			"SET async_execution=0",
+			"CALL fb_GetAsyncStatus(?)",
+			"CANCEL QUERY WHERE query_id = ?",
+			"CALL fb_GetAsyncStatus(?)",
+		}, engine.received(), name)
+	}
+}
+
+func TestAsyncSDK(t *testing.T) {
+	server := newFakeFirebolt(t, func(query string) (*fireboltgosdk.QueryResponse, error) {
+		switch query {
+		case "INSERT INTO daily_totals SELECT day, sum(amount) FROM test_orders WHERE amount > 0 GROUP BY day":
+			return &fireboltgosdk.QueryResponse{
+				Meta: []fireboltgosdk.Column{{Name: "message", Type: "text"}, {Name: "token", Type: "text"}},
+				Data: [][]interface{}{{"the query was accepted for async processing", "token-1"}},
+				Rows: 1,
+			}, nil
+		case "CALL fb_GetAsyncStatus('token-1')":
+			return &fireboltgosdk.QueryResponse{
+				Meta: []fireboltgosdk.Column{{Name: "status", Type: "text"}, {Name: "query_id", Type: "text"}, {Name: "error_message", Type: "text"}},
+				Data: [][]interface{}{{string(AsyncSucceeded), "query-1", ""}},
+				Rows: 1,
+			}, nil
+		}
+		return &fireboltgosdk.QueryResponse{}, nil
+	})
+	db := openSDKDB(t, Config{}, server)
+	// connections opened from DSN always apply async_execution, so they aren't checked
+	assert.Nil(t, db.Callback().Row().Get("firebolt:async"))
+
+	query := submitAsync(t, db)
+	assert.Equal(t, "token-1", query.Token)
+	status, err := query.Wait(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, AsyncStatus{State: AsyncSucceeded, QueryID: "query-1"}, status)
+
+	var queries []string
+	for _, request := range server.received() {
+		queries = append(queries, request.query+" async_execution="+request.params.Get("async_execution"))
+	}
+	assert.Equal(t, []string{
+		"SELECT 1 async_execution=1",
+		"INSERT INTO daily_totals SELECT day, sum(amount) FROM test_orders WHERE amount > 0 GROUP BY day async_execution=1",
+		"SELECT 1 async_execution=0",
+		"CALL fb_GetAsyncStatus('token-1') async_execution=0",
+	}, queries)
+}
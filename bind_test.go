@@ -0,0 +1,115 @@
+package firebolt
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type testBindRow struct {
+	ID   int64
+	Name string
+}
+
+var bindValues = []struct {
+	name    string
+	value   interface{}
+	driver  driver.Value
+	literal string
+}{
+	{"int", 42, int64(42), "42"},
+	{"int64", int64(-7), int64(-7), "-7"},
+	{"uint", uint(3), int64(3), "3"},
+	{"float", 1.5, 1.5, "1.500000"},
+	{"string", "o'neil", "o'neil", `'o\'neil'`},
+	{"bool", true, true, "true"},
+	{"time", time.Date(2022, 8, 1, 10, 30, 0, 0, time.UTC), time.Date(2022, 8, 1, 10, 30, 0, 0, time.UTC), "'2022-08-01 10:30:00'"},
+	{"bytes", []byte("ab"), []byte("ab"), "'ab'"},
+	{"nil", nil, nil, "NULL"},
+	{"valuer", sql.NullString{String: "x", Valid: true}, "x", "'x'"},
+}
+
+func positionalParameters(query string, args []driver.NamedValue) (*fakeResult, error) {
+	if query == "SELECT $1" {
+		return &fakeResult{columns: []string{"?column?"}, rows: [][]driver.Value{{args[0].Value}}}, nil
+	}
+	return nil, nil
+}
+
+func TestPositionalParameters(t *testing.T) {
+	for _, bind := range bindValues {
+		t.Run(bind.name, func(t *testing.T) {
+			db, fake := openFakeDB(t, Config{PositionalParameters: true}, positionalParameters)
+
+			var rows []testBindRow
+			assert.NoError(t, db.Where("id > ? AND name = ?", 0, bind.value).Find(&rows).Error)
+
+			queries := fake.received()
+			assert.Equal(t, []string{"SELECT $1", `SELECT * FROM "test_bind_rows" WHERE id > $1 AND name = $2`}, queries)
+			assert.Equal(t, []driver.NamedValue{{Ordinal: 1, Value: int64(0)}, {Ordinal: 2, Value: bind.driver}}, fake.args[1])
+		})
+	}
+}
+
+func TestPositionalParametersFallback(t *testing.T) {
+	for _, bind := range bindValues {
+		t.Run(bind.name, func(t *testing.T) {
+			db, fake := openFakeDB(t, Config{PositionalParameters: true}, func(query string, args []driver.NamedValue) (*fakeResult, error) {
+				if query == "SELECT $1" {
+					return nil, errors.New("unexpected parameter")
+				}
+				return nil, nil
+			})
+
+			var rows []testBindRow
+			assert.NoError(t, db.Where("id > ? AND name = ?", 0, bind.value).Find(&rows).Error)
+
+			assert.Equal(t, []string{"SELECT $1", `SELECT * FROM "test_bind_rows" WHERE id > ? AND name = ?`}, fake.received())
+			assert.Equal(t, []driver.NamedValue{{Ordinal: 1, Value: int64(0)}, {Ordinal: 2, Value: bind.driver}}, fake.args[1])
+		})
+	}
+}
+
+func TestPositionalParametersExplain(t *testing.T) {
+	db := openTestDB(t, Config{PositionalParameters: true}, &fakeConnPool{}, &gorm.Config{DryRun: true})
+	for _, bind := range bindValues {
+		t.Run(bind.name, func(t *testing.T) {
+			stmt := db.Where("id > ? AND name = ?", 0, bind.value).Find(&[]testBindRow{}).Statement
+			assert.Equal(t, `SELECT * FROM "test_bind_rows" WHERE id > $1 AND name = $2`, stmt.SQL.String())
+			assert.Equal(t, `SELECT * FROM "test_bind_rows" WHERE id > 0 AND name = `+bind.literal,
+				db.Dialector.Explain(stmt.SQL.String(), stmt.Vars...))
+		})
+	}
+}
+
+func TestPositionalParametersBulkLoad(t *testing.T) {
+	db := openTestDB(t, Config{PositionalParameters: true}, &fakeConnPool{}, &gorm.Config{DryRun: true})
+	rows := testBulkRows()
+
+	stmt := BulkLoad(db, &rows, BulkLoadOptions{Stage: LocalStage{Dir: "/data"}, FileName: "rows.csv"}).Statement
+	sql := `COPY INTO "test_bulk_rows" ("id" $1,"name" $2,"score" $3,"active" $4,"payload" $5,"created_at" $6) FROM '/data/rows.csv' WITH TYPE = CSV HEADER = TRUE`
+	assert.Equal(t, sql, stmt.SQL.String())
+	assert.Equal(t, sql, db.Dialector.Explain(stmt.SQL.String(), stmt.Vars...))
+
+	// $N map the file columns, so the options are bound to ? placeholders
+	stage := S3Stage{Bucket: "bucket", Region: "us-east-1", AccessKeyID: "key", SecretAccessKey: "secret"}
+	stmt = BulkLoad(db, &rows, BulkLoadOptions{Stage: stage, FileName: "rows.csv"}).Statement
+	assert.Equal(t, `COPY INTO "test_bulk_rows" ("id" $1,"name" $2,"score" $3,"active" $4,"payload" $5,"created_at" $6) FROM 's3://bucket/rows.csv' WITH TYPE = CSV HEADER = TRUE CREDENTIALS = (AWS_KEY_ID = ? AWS_SECRET_KEY = ?)`, stmt.SQL.String())
+	assert.Equal(t, `COPY INTO "test_bulk_rows" ("id" $1,"name" $2,"score" $3,"active" $4,"payload" $5,"created_at" $6) FROM 's3://bucket/rows.csv' WITH TYPE = CSV HEADER = TRUE CREDENTIALS = (AWS_KEY_ID = '***' AWS_SECRET_KEY = '***')`,
+		db.Dialector.Explain(stmt.SQL.String(), stmt.Vars...))
+}
+
+func TestPositionalParametersSplitInsert(t *testing.T) {
+	db := openTestDB(t, Config{PositionalParameters: true, MaxInsertRows: 1}, &fakeConnPool{}, &gorm.Config{DryRun: true})
+
+	rows := []testBindRow{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}
+	stmt := db.Create(&rows).Statement
+	assert.Equal(t, `INSERT INTO "test_bind_rows" ("name","id") VALUES ($1,$2);`+"\n"+`INSERT INTO "test_bind_rows" ("name","id") VALUES ($3,$4)`, stmt.SQL.String())
+	assert.Equal(t, `INSERT INTO "test_bind_rows" ("name","id") VALUES ('a',1);`+"\n"+`INSERT INTO "test_bind_rows" ("name","id") VALUES ('b',2)`,
+		db.Dialector.Explain(stmt.SQL.String(), stmt.Vars...))
+}
@@ -0,0 +1,223 @@
+package firebolt
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// BulkFormat is a file format used to stage rows for COPY FROM
+type BulkFormat string
+
+const (
+	BulkCSV     BulkFormat = "CSV"
+	BulkParquet BulkFormat = "PARQUET"
+)
+
+type BulkLoadOptions struct {
+	// Format of the staged file, CSV by default
+	Format BulkFormat
+	// Stage the file is uploaded to, required
+	Stage Stage
+	// FileName of the staged file, generated from the table name when empty
+	FileName string
+	// KeepStaged leaves the staged file after loading, by default it is removed
+	KeepStaged bool
+}
+
+// BulkLoad loads rows through COPY FROM: rows are serialized to a file, uploaded to the stage
+// and copied into the model's table with columns mapped by the GORM schema.
+// RowsAffected of the result is the number of loaded rows
+func BulkLoad(db *gorm.DB, value interface{}, opts BulkLoadOptions) *gorm.DB {
+	tx := db.Session(&gorm.Session{})
+	stmt := &gorm.Statement{DB: tx, Context: tx.Statement.Context}
+	if err := stmt.Parse(value); err != nil {
+		_ = tx.AddError(err)
+		return tx
+	}
+	if opts.Stage == nil {
+		_ = tx.AddError(errors.New("BulkLoad requires a stage"))
+		return tx
+	}
+	if opts.Format == "" {
+		opts.Format = BulkCSV
+	}
+	if opts.Format != BulkCSV && opts.Format != BulkParquet {
+		_ = tx.AddError(fmt.Errorf("unsupported bulk load format %s", opts.Format))
+		return tx
+	}
+	if opts.FileName == "" {
+		opts.FileName = fmt.Sprintf("%s_%d.%s", stmt.Table, time.Now().UnixNano(), strings.ToLower(string(opts.Format)))
+	}
+
+	fields := make([]*schema.Field, 0, len(stmt.Schema.DBNames))
+	for _, dbName := range stmt.Schema.DBNames {
+		if field := stmt.Schema.FieldsByDBName[dbName]; field.Creatable {
+			fields = append(fields, field)
+		}
+	}
+
+	copySQL, vars := copyFromSQL(tx, stmt.Table, fields, opts)
+	if tx.DryRun {
+		return tx.Set(questionMarksKey, true).Exec(copySQL, vars...)
+	}
+
+	file, err := os.CreateTemp("", "firebolt-bulk-*")
+	if err != nil {
+		_ = tx.AddError(err)
+		return tx
+	}
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
+
+	rows, err := writeBulkFile(stmt, value, fields, file, opts.Format)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err == nil {
+		err = opts.Stage.Put(stmt.Context, opts.FileName, file)
+	}
+	if err != nil {
+		_ = tx.AddError(err)
+		return tx
+	}
+
+	result := tx.Set(questionMarksKey, true).Exec(copySQL, vars...)
+	if !opts.KeepStaged {
+		_ = result.AddError(opts.Stage.Remove(stmt.Context, opts.FileName))
+	}
+	if result.Error == nil {
+		result.RowsAffected = rows
+	}
+	return result
+}
+
+// copyFromSQL builds COPY statement loading the staged file into the table and returns it with the values of the stage's options,
+// the location is written as a literal since $N placeholders map the file columns, the options are bound to ? placeholders
+func copyFromSQL(db *gorm.DB, table string, fields []*schema.Field, opts BulkLoadOptions) (string, []interface{}) {
+	var sql strings.Builder
+	sql.WriteString("COPY INTO ")
+	db.Dialector.QuoteTo(&sql, table)
+	sql.WriteString(" (")
+	for idx, field := range fields {
+		if idx > 0 {
+			sql.WriteByte(',')
+		}
+		db.Dialector.QuoteTo(&sql, field.DBName)
+		fmt.Fprintf(&sql, " $%d", idx+1)
+	}
+	fmt.Fprintf(&sql, ") FROM %s WITH TYPE = %s", quoteString(opts.Stage.Location(opts.FileName)), opts.Format)
+	if opts.Format == BulkCSV {
+		sql.WriteString(" HEADER = TRUE")
+	}
+	options, vars := opts.Stage.CopyOptions()
+	if options != "" {
+		sql.WriteString(" " + options)
+	}
+	return sql.String(), vars
+}
+
+// writeBulkFile serializes rows of value into w and returns the number of written rows
+func writeBulkFile(stmt *gorm.Statement, value interface{}, fields []*schema.Field, w io.Writer, format BulkFormat) (int64, error) {
+	var writeRow func(values []interface{}) error
+	var closeWriter func() error
+
+	switch format {
+	case BulkParquet:
+		columns := make([]*parquetColumn, 0, len(fields))
+		for _, field := range fields {
+			columns = append(columns, parquetColumnOf(field))
+		}
+		writer, err := newParquetWriter(w, columns)
+		if err != nil {
+			return 0, err
+		}
+		writeRow, closeWriter = writer.WriteRow, writer.Close
+	default:
+		var err error
+		if writeRow, closeWriter, err = newCSVWriter(w, fields); err != nil {
+			return 0, err
+		}
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(value))
+	elems := []reflect.Value{rv}
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		elems = elems[:0]
+		for i := 0; i < rv.Len(); i++ {
+			elems = append(elems, rv.Index(i))
+		}
+	}
+
+	var rows int64
+	values := make([]interface{}, len(fields))
+	for _, elem := range elems {
+		for idx, field := range fields {
+			value, isZero := field.ValueOf(stmt.Context, elem)
+			if isZero && field.DataType == schema.Time && (field.AutoCreateTime == schema.UnixTime || field.AutoUpdateTime == schema.UnixTime) {
+				value = stmt.DB.NowFunc()
+			}
+			values[idx] = value
+		}
+		if err := writeRow(values); err != nil {
+			return rows, err
+		}
+		rows++
+	}
+	return rows, closeWriter()
+}
+
+// newCSVWriter writes the header of the fields' columns and returns functions writing a row and flushing the writer
+func newCSVWriter(w io.Writer, fields []*schema.Field) (writeRow func(values []interface{}) error, flush func() error, err error) {
+	writer := csv.NewWriter(w)
+	header := make([]string, 0, len(fields))
+	for _, field := range fields {
+		header = append(header, field.DBName)
+	}
+	if err = writer.Write(header); err != nil {
+		return nil, nil, err
+	}
+	record := make([]string, len(fields))
+	writeRow = func(values []interface{}) error {
+		for idx, value := range values {
+			var err error
+			if record[idx], err = csvValue(value); err != nil {
+				return fmt.Errorf("column %s: %w", fields[idx].DBName, err)
+			}
+		}
+		return writer.Write(record)
+	}
+	flush = func() error {
+		writer.Flush()
+		return writer.Error()
+	}
+	return writeRow, flush, nil
+}
+
+// csvValue formats value the way Firebolt parses it from CSV, NULL is written as an empty field
+func csvValue(value interface{}) (string, error) {
+	value, err := driverValue(value)
+	if err != nil || value == nil {
+		return "", err
+	}
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return `\x` + hex.EncodeToString(v), nil
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999999-07:00"), nil
+	}
+	return fmt.Sprint(value), nil
+}
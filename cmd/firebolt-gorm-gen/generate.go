@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"reflect"
+	"sort"
+	"strings"
+
+	firebolt "github.com/firebolt-db/firebolt-gorm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+type options struct {
+	// Package is the package name of the generated file
+	Package string
+	// Tables to generate models for, all the tables of the database when empty
+	Tables []string
+	// TablePrefix is trimmed from the table names to name the models
+	TablePrefix string
+	// SingularTable keeps the table names as they are, they are singularized to name the models otherwise
+	SingularTable bool
+	// NullablePointers generates nullable columns as pointers
+	NullablePointers bool
+}
+
+// field is a field of a generated model
+type field struct {
+	Name string
+	Type string
+	Tag  string
+}
+
+// model is a generated model of a table
+type model struct {
+	Name      string
+	Table     string
+	TableType firebolt.TableType
+	Fields    []field
+	// Comments describe the indexes which can't be expressed with gorm tags
+	Comments []string
+}
+
+// generate reads the tables from information_schema and returns the source of their models
+func generate(db *gorm.DB, opts options) ([]byte, error) {
+	migrator, ok := db.Migrator().(firebolt.Migrator)
+	if !ok {
+		return nil, errors.New("firebolt-gorm-gen requires a firebolt database")
+	}
+	tables := opts.Tables
+	if len(tables) == 0 {
+		var err error
+		if tables, err = migrator.GetTables(); err != nil {
+			return nil, err
+		}
+		sort.Strings(tables)
+	}
+
+	naming := schema.NamingStrategy{TablePrefix: opts.TablePrefix, SingularTable: opts.SingularTable}
+	models := make([]model, 0, len(tables))
+	// imports holds the names of the imported packages by their paths
+	imports := map[string]string{}
+	for _, table := range tables {
+		m, err := readModel(migrator, naming, opts, strings.TrimSpace(table))
+		if err != nil {
+			return nil, fmt.Errorf("table %s: %w", table, err)
+		}
+		for _, f := range m.Fields {
+			if strings.Contains(f.Type, "time.") {
+				imports["time"] = ""
+			}
+			if strings.Contains(f.Type, "firebolt.") {
+				imports["github.com/firebolt-db/firebolt-gorm"] = "firebolt"
+			}
+		}
+		if m.TableType == firebolt.DimensionTable {
+			imports["github.com/firebolt-db/firebolt-gorm"] = "firebolt"
+		}
+		models = append(models, m)
+	}
+
+	var source bytes.Buffer
+	source.WriteString("// Code generated by firebolt-gorm-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&source, "package %s\n", opts.Package)
+	if len(imports) > 0 {
+		paths := make([]string, 0, len(imports))
+		for path := range imports {
+			paths = append(paths, path)
+		}
+		// standard packages go first
+		sort.Slice(paths, func(i, j int) bool {
+			iStd, jStd := !strings.Contains(paths[i], "."), !strings.Contains(paths[j], ".")
+			if iStd != jStd {
+				return iStd
+			}
+			return paths[i] < paths[j]
+		})
+		source.WriteString("\nimport (\n")
+		for idx, path := range paths {
+			if idx > 0 && !strings.Contains(paths[idx-1], ".") && strings.Contains(path, ".") {
+				source.WriteString("\n")
+			}
+			if name := imports[path]; name != "" {
+				fmt.Fprintf(&source, "\t%s %q\n", name, path)
+			} else {
+				fmt.Fprintf(&source, "\t%q\n", path)
+			}
+		}
+		source.WriteString(")\n")
+	}
+	for _, m := range models {
+		writeModel(&source, m)
+	}
+	return format.Source(source.Bytes())
+}
+
+func readModel(migrator firebolt.Migrator, naming schema.NamingStrategy, opts options, table string) (m model, err error) {
+	m = model{Name: naming.SchemaName(table), Table: table}
+	if m.TableType, err = migrator.GetTableType(table); err != nil {
+		return m, err
+	}
+	columnTypes, err := migrator.ColumnTypes(table)
+	if err != nil {
+		return m, err
+	}
+	if len(columnTypes) == 0 {
+		return m, errors.New("no columns found")
+	}
+	indexes, err := migrator.GetIndexes(table)
+	if err != nil {
+		return m, err
+	}
+
+	// primary index columns go first in the index order, since gorm orders the primary index by fields
+	var primaryIndex []string
+	for _, index := range indexes {
+		if primary, _ := index.PrimaryKey(); primary {
+			primaryIndex = index.Columns()
+		} else {
+			m.Comments = append(m.Comments, fmt.Sprintf("%s index %s: %s",
+				strings.ToLower(index.Option()), index.Name(), strings.Join(index.Columns(), ", ")))
+		}
+	}
+	if primaryIndex == nil {
+		for _, columnType := range columnTypes {
+			if primary, _ := columnType.PrimaryKey(); primary {
+				primaryIndex = append(primaryIndex, columnType.Name())
+			}
+		}
+	}
+	position := make(map[string]int, len(primaryIndex))
+	for idx, column := range primaryIndex {
+		position[column] = idx
+	}
+	sort.SliceStable(columnTypes, func(i, j int) bool {
+		pi, iPrimary := position[columnTypes[i].Name()]
+		pj, jPrimary := position[columnTypes[j].Name()]
+		if iPrimary && jPrimary {
+			return pi < pj
+		}
+		return iPrimary && !jPrimary
+	})
+
+	fieldNaming := schema.NamingStrategy{SingularTable: true}
+	for _, columnType := range columnTypes {
+		_, primary := position[columnType.Name()]
+		m.Fields = append(m.Fields, fieldOf(columnType, fieldNaming.SchemaName(columnType.Name()), primary, opts))
+	}
+	return m, nil
+}
+
+func fieldOf(columnType gorm.ColumnType, name string, primary bool, opts options) field {
+	scanType := columnType.ScanType()
+	_, _, isDecimal := columnType.DecimalSize()
+	isArray := scanType.Kind() == reflect.Slice && scanType.Elem().Kind() != reflect.Uint8
+	goType := goTypeOf(scanType)
+	switch {
+	case isArray:
+		// the SDK returns arrays as []driver.Value, which firebolt.Array scans into the element type
+		goType = "firebolt.Array[" + goTypeOf(scanType.Elem()) + "]"
+	case isDecimal:
+		// DECIMAL is kept as its digits, float64 would round it
+		goType = "string"
+	}
+	nullable, _ := columnType.Nullable()
+	if nullable && opts.NullablePointers && scanType.Kind() != reflect.Slice && scanType.Kind() != reflect.Interface {
+		goType = "*" + goType
+	}
+
+	tags := []string{"column:" + columnType.Name()}
+	dataType, _ := columnType.ColumnType()
+	if isArray || isDecimal || scanType.Kind() == reflect.Interface {
+		tags = append(tags, "type:"+dataType)
+	}
+	if primary {
+		tags = append(tags, "primarykey")
+	}
+	if partitioner, ok := columnType.(interface{ Partition() (bool, bool) }); ok {
+		if partition, _ := partitioner.Partition(); partition {
+			tags = append(tags, "partition")
+		}
+	}
+	if !nullable {
+		tags = append(tags, "not null")
+	}
+	return field{Name: name, Type: goType, Tag: fmt.Sprintf("`gorm:%q`", strings.Join(tags, ";"))}
+}
+
+// goTypeOf returns the Go source of the type, []uint8 is written as []byte
+func goTypeOf(t reflect.Type) string {
+	if t.Kind() != reflect.Slice {
+		return t.String()
+	}
+	if t.Elem().Kind() == reflect.Uint8 {
+		return "[]byte"
+	}
+	return "[]" + goTypeOf(t.Elem())
+}
+
+func writeModel(source *bytes.Buffer, m model) {
+	fmt.Fprintf(source, "\n// %s is a model of %s table\n", m.Name, m.Table)
+	for _, comment := range m.Comments {
+		fmt.Fprintf(source, "//\n// %s\n", comment)
+	}
+	fmt.Fprintf(source, "type %s struct {\n", m.Name)
+	for _, f := range m.Fields {
+		fmt.Fprintf(source, "\t%s %s %s\n", f.Name, f.Type, f.Tag)
+	}
+	source.WriteString("}\n")
+
+	fmt.Fprintf(source, "\nfunc (%s) TableName() string {\n\treturn %q\n}\n", m.Name, m.Table)
+	if m.TableType == firebolt.DimensionTable {
+		fmt.Fprintf(source, "\nfunc (%s) TableType() firebolt.TableType {\n\treturn firebolt.DimensionTable\n}\n", m.Name)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	firebolt "github.com/firebolt-db/firebolt-gorm"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// fakeTable is a table of fakeSchema as described by information_schema
+type fakeTable struct {
+	tableType string
+	// columns are column_name, data_type, is_nullable, is_in_primary_index, is_in_partition_expr
+	columns [][]driver.Value
+	// indexes are index_name, index_type, index_definition
+	indexes [][]driver.Value
+}
+
+var fakeSchema = map[string]fakeTable{
+	"orders": {
+		tableType: "FACT",
+		columns: [][]driver.Value{
+			{"id", "BIGINT", "NO", "YES", "NO"},
+			{"customer", "TEXT", "YES", "NO", "NO"},
+			{"amount", "DECIMAL(38,2)", "NO", "NO", "NO"},
+			{"tags", "ARRAY(TEXT)", "NO", "NO", "NO"},
+			{"day", "DATE", "NO", "YES", "YES"},
+			{"created_at", "TIMESTAMPTZ", "YES", "NO", "NO"},
+		},
+		indexes: [][]driver.Value{
+			{"orders_primary", "primary", "(day, id)"},
+			{"orders_daily", "aggregating", "[day, customer, SUM(amount), COUNT(DISTINCT id)]"},
+		},
+	},
+	"countries": {
+		tableType: "DIMENSION",
+		columns: [][]driver.Value{
+			{"code", "TEXT", "NO", "YES", "NO"},
+			{"name", "TEXT", "YES", "NO", "NO"},
+			{"population", "BIGINT", "YES", "NO", "NO"},
+			{"flag", "BYTEA", "YES", "NO", "NO"},
+			{"scores", "ARRAY(INT NULL)", "NO", "NO", "NO"},
+			{"is_active", "BOOLEAN", "NO", "NO", "NO"},
+		},
+		indexes: [][]driver.Value{
+			{"countries_primary", "primary", `("code")`},
+			{"countries_join", "join", "(code, name)"},
+		},
+	},
+	"fb_event_log": {
+		tableType: "FACT",
+		columns: [][]driver.Value{
+			{"event_id", "BIGINT", "NO", "YES", "NO"},
+			{"url", "TEXT", "YES", "NO", "NO"},
+			{"score", "REAL", "YES", "NO", "NO"},
+			{"payload", "GEOGRAPHY", "YES", "NO", "NO"},
+			{"price", "NUMERIC(10,4)", "YES", "NO", "NO"},
+			{"matrix", "ARRAY(ARRAY(INT))", "NO", "NO", "NO"},
+			{"labels", "ARRAY", "YES", "NO", "NO"},
+			{"attributes", "ARRAY()", "YES", "NO", "NO"},
+		},
+	},
+}
+
+// fakeConnector answers information_schema queries from fakeSchema
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return fakeConn{}, nil
+}
+
+func (fakeConnector) Driver() driver.Driver {
+	return nil
+}
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported by fakeConn")
+}
+
+func (fakeConn) Close() error {
+	return nil
+}
+
+func (fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported by fakeConn")
+}
+
+func (fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if query == "SELECT table_name FROM information_schema.tables" {
+		rows := &fakeRows{columns: []string{"table_name"}}
+		for name := range fakeSchema {
+			rows.rows = append(rows.rows, []driver.Value{name})
+		}
+		return rows, nil
+	}
+
+	table, ok := fakeSchema[args[0].Value.(string)]
+	if !ok {
+		return &fakeRows{}, nil
+	}
+	switch {
+	case strings.HasPrefix(query, "SELECT table_type FROM information_schema.tables"):
+		return &fakeRows{columns: []string{"table_type"}, rows: [][]driver.Value{{table.tableType}}}, nil
+	case strings.Contains(query, "FROM information_schema.columns"):
+		return &fakeRows{columns: []string{"column_name", "data_type", "is_nullable", "is_in_primary_index", "is_in_partition_expr"}, rows: table.columns}, nil
+	case strings.Contains(query, "FROM information_schema.indexes"):
+		return &fakeRows{columns: []string{"index_name", "index_type", "index_definition"}, rows: table.indexes}, nil
+	}
+	return nil, errors.New("unexpected query " + query)
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestGenerate(t *testing.T) {
+	db, err := gorm.Open(&firebolt.Dialector{Config: &firebolt.Config{}, Conn: sql.OpenDB(fakeConnector{})},
+		&gorm.Config{DisableAutomaticPing: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("failed to open gorm session: %v", err)
+	}
+
+	for name, opts := range map[string]options{
+		"models": {Package: "models", Tables: []string{"orders", "countries"}, NullablePointers: true},
+		"events": {Package: "events", Tables: []string{"fb_event_log"}, TablePrefix: "fb_", SingularTable: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			source, err := generate(db, opts)
+			if !assert.NoError(t, err) {
+				return
+			}
+			golden := filepath.Join("testdata", name+".golden")
+			if *update {
+				assert.NoError(t, os.WriteFile(golden, source, 0o644))
+			}
+			expected, err := os.ReadFile(golden)
+			assert.NoError(t, err)
+			assert.Equal(t, string(expected), string(source))
+		})
+	}
+
+	source, err := generate(db, options{Package: "models"})
+	assert.NoError(t, err)
+	assert.Less(t, strings.Index(string(source), "type Country struct"), strings.Index(string(source), "type FbEventLog struct"))
+	assert.Less(t, strings.Index(string(source), "type FbEventLog struct"), strings.Index(string(source), "type Order struct"))
+
+	_, err = generate(db, options{Package: "models", Tables: []string{"missing"}})
+	assert.EqualError(t, err, "table missing: sql: no rows in result set")
+}
@@ -0,0 +1,55 @@
+// Command firebolt-gorm-gen generates GORM models for the tables of a Firebolt database, e.g.
+//
+//	firebolt-gorm-gen -dsn "$FIREBOLT_DSN" -package models -out models/models.go
+//
+// The tables, their columns and indexes are read from information_schema through the firebolt Migrator
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	firebolt "github.com/firebolt-db/firebolt-gorm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func main() {
+	var (
+		dsn    = flag.String("dsn", os.Getenv("FIREBOLT_DSN"), "connection string of the database, $FIREBOLT_DSN by default")
+		out    = flag.String("out", "", "file the models are written to, stdout by default")
+		tables = flag.String("tables", "", "comma separated tables to generate models for, all the tables by default")
+		opts   options
+	)
+	flag.StringVar(&opts.Package, "package", "models", "package name of the generated file")
+	flag.StringVar(&opts.TablePrefix, "table-prefix", "", "prefix of the table names left out of the model names")
+	flag.BoolVar(&opts.SingularTable, "singular-table", false, "table names are singular, model names are singularized otherwise")
+	flag.BoolVar(&opts.NullablePointers, "nullable-pointers", true, "nullable columns are generated as pointers")
+	flag.Parse()
+
+	if *dsn == "" {
+		fmt.Fprintln(os.Stderr, "firebolt-gorm-gen: -dsn is required")
+		os.Exit(2)
+	}
+	if *tables != "" {
+		opts.Tables = strings.Split(*tables, ",")
+	}
+
+	db, err := gorm.Open(firebolt.Open(*dsn), &gorm.Config{Logger: logger.Discard})
+	if err == nil {
+		var source []byte
+		if source, err = generate(db, opts); err == nil {
+			if *out == "" {
+				_, err = os.Stdout.Write(source)
+			} else {
+				err = os.WriteFile(*out, source, 0o644)
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "firebolt-gorm-gen:", err)
+		os.Exit(1)
+	}
+}
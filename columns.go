@@ -0,0 +1,32 @@
+package firebolt
+
+import (
+	"strings"
+
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/utils"
+)
+
+// columnOf returns column of the name, names which aren't plain identifiers are kept as raw SQL
+func columnOf(name string) clause.Column {
+	return columnsOf([]string{name})[0]
+}
+
+// columnsOf converts names to columns, names which aren't plain identifiers are kept as raw SQL
+func columnsOf(names []string) []clause.Column {
+	columns := make([]clause.Column, 0, len(names))
+	for _, name := range names {
+		fields := strings.FieldsFunc(name, utils.IsValidDBNameChar)
+		columns = append(columns, clause.Column{Name: name, Raw: len(fields) != 1})
+	}
+	return columns
+}
+
+func writeColumns(builder clause.Builder, columns []clause.Column) {
+	for idx, column := range columns {
+		if idx > 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteQuoted(column)
+	}
+}
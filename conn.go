@@ -0,0 +1,254 @@
+package firebolt
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type queryLabelKey struct{}
+
+// WithQueryLabel returns a context labelling the statements executed with it, the label is sent as query_label setting
+// and shows up in Firebolt query history, e.g.
+//
+//	db.WithContext(firebolt.WithQueryLabel(ctx, "request-42")).Find(&orders)
+func WithQueryLabel(ctx context.Context, label string) context.Context {
+	return context.WithValue(ctx, queryLabelKey{}, label)
+}
+
+// QueryLabelFrom returns the query label of the context
+func QueryLabelFrom(ctx context.Context) (string, bool) {
+	label, ok := ctx.Value(queryLabelKey{}).(string)
+	return label, ok
+}
+
+// dsnConnector opens driver connections for the DSN
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// sessionConnector wraps driver connections with sessionConn
+type sessionConnector struct {
+	driver.Connector
+	// cancelQueries cancels statements on the engine when their context is done
+	cancelQueries bool
+	retry         *RetryPolicy
+}
+
+func (c sessionConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	session := &sessionConn{Conn: conn, retry: c.retry}
+	if c.cancelQueries {
+		session.cancel = c.cancelQuery
+	}
+	return session, nil
+}
+
+// cancelQuery cancels the query on the engine through a new connection, since the statement's one is busy
+func (c sessionConnector) cancelQuery(queryID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), CancelQueryTimeout)
+	defer cancel()
+
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	execer, ok := conn.(driver.ExecerContext)
+	if !ok {
+		return fmt.Errorf("CANCEL QUERY isn't supported by %T", conn)
+	}
+	_, err = execer.ExecContext(ctx, "CANCEL QUERY WHERE query_id = ?", []driver.NamedValue{{Ordinal: 1, Value: queryID}})
+	return err
+}
+
+// CancelQueryTimeout limits the time of cancelling a query on the engine
+var CancelQueryTimeout = 10 * time.Second
+
+// QueryCanceledError is returned when the context of a statement is done before the statement finishes,
+// it unwraps to the context error
+type QueryCanceledError struct {
+	// QueryID is the ID the statement was sent with
+	QueryID string
+	// Cause is the context error
+	Cause error
+	// CancelErr is the error of cancelling the query on the engine, if any
+	CancelErr error
+}
+
+func (e *QueryCanceledError) Error() string {
+	if e.CancelErr != nil {
+		return fmt.Sprintf("query %s was canceled: %v, cancelling it on the engine failed: %v", e.QueryID, e.Cause, e.CancelErr)
+	}
+	return fmt.Sprintf("query %s was canceled: %v", e.QueryID, e.Cause)
+}
+
+func (e *QueryCanceledError) Unwrap() error {
+	return e.Cause
+}
+
+// sessionConn keeps the settings of a connection in line with the context of the executed statements,
+// a setting is sent when a statement needs a value different from the previous statement's one
+type sessionConn struct {
+	driver.Conn
+	label string
+	async bool
+	// cancel cancels a query on the engine, statements get a query_id to be cancelled by when it is set
+	cancel func(queryID string) error
+	retry  *RetryPolicy
+}
+
+func (c *sessionConn) applySettings(ctx context.Context) (queryID string, err error) {
+	label, _ := QueryLabelFrom(ctx)
+	label = strings.TrimSpace(label)
+	if label != c.label {
+		// the SDK splits SET statements at '=' and multiple statements at ';'
+		if strings.ContainsAny(label, "=;\n") {
+			return "", fmt.Errorf("query label %q can't contain '=', ';' or new lines", label)
+		}
+		if err = c.set(ctx, "query_label", label); err != nil {
+			return "", err
+		}
+		c.label = label
+	}
+
+	if async := isAsyncExecution(ctx); async != c.async {
+		value := "0"
+		if async {
+			value = "1"
+		}
+		if err = c.set(ctx, "async_execution", value); err != nil {
+			return "", err
+		}
+		c.async = async
+	}
+
+	// every statement gets a new ID, the previous one stays set on the connection otherwise
+	if c.cancel != nil {
+		queryID = newQueryID()
+		if err = c.set(ctx, "query_id", queryID); err != nil {
+			return "", err
+		}
+	}
+	return queryID, nil
+}
+
+// set sets the setting for the following statements of the connection, an empty value clears it
+func (c *sessionConn) set(ctx context.Context, name, value string) error {
+	if value == "" {
+		value = "''"
+	}
+	return c.exec(ctx, "SET "+name+"="+value)
+}
+
+// exec sends the statement without applying the settings of ctx
+func (c *sessionConn) exec(ctx context.Context, query string) error {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return fmt.Errorf("%s isn't supported by %T", sqlOperation(query), c.Conn)
+	}
+	_, err := execer.ExecContext(ctx, query, nil)
+	return err
+}
+
+// watchCancel cancels the query on the engine when ctx is done before the returned stop function is called,
+// stop turns the error of the statement into *QueryCanceledError in that case
+func (c *sessionConn) watchCancel(ctx context.Context, queryID string) (stop func(err error) error) {
+	if queryID == "" || ctx.Done() == nil {
+		return func(err error) error {
+			return err
+		}
+	}
+
+	finished := make(chan struct{})
+	canceled := make(chan error, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			canceled <- c.cancel(queryID)
+		case <-finished:
+			close(canceled)
+		}
+	}()
+	return func(err error) error {
+		close(finished)
+		cancelErr, wasCanceled := <-canceled
+		if !wasCanceled && err != nil && ctx.Err() != nil {
+			// the statement failed as ctx was done, before the watcher noticed it
+			cancelErr, wasCanceled = c.cancel(queryID), true
+		}
+		if wasCanceled && err != nil {
+			return &QueryCanceledError{QueryID: queryID, Cause: ctx.Err(), CancelErr: cancelErr}
+		}
+		return err
+	}
+}
+
+func (c *sessionConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (result driver.Result, err error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	err = c.retry.run(ctx, query, func() error {
+		queryID, err := c.applySettings(ctx)
+		if err != nil {
+			return err
+		}
+		stop := c.watchCancel(ctx, queryID)
+		result, err = execer.ExecContext(ctx, query, args)
+		return stop(err)
+	})
+	return result, err
+}
+
+func (c *sessionConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (rows driver.Rows, err error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	err = c.retry.run(ctx, query, func() error {
+		queryID, err := c.applySettings(ctx)
+		if err != nil {
+			return err
+		}
+		stop := c.watchCancel(ctx, queryID)
+		rows, err = queryer.QueryContext(ctx, query, args)
+		return stop(err)
+	})
+	return rows, err
+}
+
+func (c *sessionConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if _, err := c.applySettings(ctx); err != nil {
+		return nil, err
+	}
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+// newQueryID returns a random UUID
+func newQueryID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:])
+}
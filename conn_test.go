@@ -0,0 +1,134 @@
+package firebolt
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestQueryLabel(t *testing.T) {
+	fake := &fakeDriver{}
+	pool := sql.OpenDB(sessionConnector{Connector: fake})
+	db := openTestDB(t, Config{}, pool, &gorm.Config{})
+	ctx := WithQueryLabel(context.Background(), "request-42")
+
+	var orders []testOrder
+	assert.NoError(t, db.WithContext(ctx).Where("customer = ?", "acme").Find(&orders).Error)
+	assert.NoError(t, db.WithContext(ctx).Exec("DELETE FROM test_orders").Error)
+	assert.NoError(t, db.WithContext(WithQueryLabel(ctx, "request-43")).Find(&orders).Error)
+	assert.NoError(t, db.Find(&orders).Error)
+	assert.NoError(t, db.WithContext(ctx).Find(&orders).Error)
+
+	assert.Equal(t, []string{
+		"SET query_label=request-42",
+		`SELECT * FROM "test_orders" WHERE customer = ?`,
+		"DELETE FROM test_orders",
+		"SET query_label=request-43",
+		`SELECT * FROM "test_orders"`,
+		"SET query_label=''",
+		`SELECT * FROM "test_orders"`,
+		"SET query_label=request-42",
+		`SELECT * FROM "test_orders"`,
+	}, fake.received())
+	// the label is cleared on the same connection
+	assert.Equal(t, 1, fake.connects)
+
+	label, ok := QueryLabelFrom(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "request-42", label)
+
+	err := db.WithContext(WithQueryLabel(ctx, "user=42")).Find(&orders).Error
+	assert.EqualError(t, err, `query label "user=42" can't contain '=', ';' or new lines`)
+}
+
+func TestQueryLabelSDK(t *testing.T) {
+	server := newFakeFirebolt(t, nil)
+	db := openSDKDB(t, Config{}, server)
+	ctx := WithQueryLabel(context.Background(), "request-42")
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, db.WithContext(ctx).Find(&[]testOrder{}).Error)
+		assert.NoError(t, db.Find(&[]testOrder{}).Error)
+	}
+
+	// the SDK validates every SET with a request of its own, the label is cleared with '' on the same connection
+	requests := server.received()
+	if assert.Len(t, requests, 8) {
+		for idx := 0; idx < len(requests); idx += 2 {
+			label := "request-42"
+			if idx%4 == 2 {
+				label = "''"
+			}
+			assert.Equal(t, "SELECT 1", requests[idx].query)
+			assert.Equal(t, label, requests[idx].params.Get("query_label"))
+			assert.Equal(t, `SELECT * FROM "test_orders"`, requests[idx+1].query)
+			assert.Equal(t, label, requests[idx+1].params.Get("query_label"))
+		}
+	}
+	assert.Equal(t, 1, server.connects)
+}
+
+func TestCancelQueries(t *testing.T) {
+	release := make(chan struct{})
+	fake := &fakeDriver{handler: func(query string, args []driver.NamedValue) (*fakeResult, error) {
+		switch query {
+		case `SELECT * FROM "test_orders"`:
+			// the engine runs the query until it is cancelled
+			<-release
+			return nil, errors.New("error during query execution: Query was cancelled")
+		case "CANCEL QUERY WHERE query_id = ?":
+			close(release)
+		}
+		return nil, nil
+	}}
+	db := openTestDB(t, Config{}, sql.OpenDB(sessionConnector{Connector: fake, cancelQueries: true}), &gorm.Config{})
+
+	assert.NoError(t, db.Where("id = ?", 1).Find(&[]testOrder{}).Error)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := db.WithContext(ctx).Find(&[]testOrder{}).Error
+
+	var canceled *QueryCanceledError
+	if assert.ErrorAs(t, err, &canceled) {
+		assert.NoError(t, canceled.CancelErr)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, ErrorClassTimeout, ClassifyError(err))
+
+		queries := fake.received()
+		if assert.Len(t, queries, 5) {
+			assert.True(t, strings.HasPrefix(queries[0], "SET query_id="))
+			assert.Equal(t, `SELECT * FROM "test_orders" WHERE id = ?`, queries[1])
+			assert.Equal(t, "SET query_id="+canceled.QueryID, queries[2])
+			assert.Equal(t, []string{`SELECT * FROM "test_orders"`, "CANCEL QUERY WHERE query_id = ?"}, queries[3:])
+			assert.Equal(t, []driver.NamedValue{{Ordinal: 1, Value: canceled.QueryID}}, fake.args[4])
+			assert.NotEqual(t, queries[0], queries[2])
+		}
+	}
+}
+
+func TestCancelQueriesSDK(t *testing.T) {
+	server := newFakeFirebolt(t, nil)
+	db := openSDKDB(t, Config{CancelQueries: true}, server)
+
+	assert.NoError(t, db.Find(&[]testOrder{}).Error)
+	assert.NoError(t, db.Exec("DELETE FROM test_orders").Error)
+
+	// every statement gets a query_id of its own, set on the connection before it
+	requests := server.received()
+	if assert.Len(t, requests, 4) {
+		assert.Equal(t, []string{"SELECT 1", `SELECT * FROM "test_orders"`, "SELECT 1", "DELETE FROM test_orders"},
+			[]string{requests[0].query, requests[1].query, requests[2].query, requests[3].query})
+		assert.Len(t, requests[1].params.Get("query_id"), 36)
+		assert.Equal(t, requests[0].params.Get("query_id"), requests[1].params.Get("query_id"))
+		assert.Equal(t, requests[2].params.Get("query_id"), requests[3].params.Get("query_id"))
+		assert.NotEqual(t, requests[1].params.Get("query_id"), requests[3].params.Get("query_id"))
+	}
+}
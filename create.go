@@ -0,0 +1,248 @@
+package firebolt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+)
+
+// ChunkError describes a failed chunk of a split multi-row insert
+type ChunkError struct {
+	// Index is the position of the chunk in the insert
+	Index int
+	// Offset is the index of the first row of the chunk in the inserted slice
+	Offset int
+	// Rows is the number of rows in the chunk
+	Rows int
+	Err  error
+}
+
+func (e ChunkError) Error() string {
+	return fmt.Sprintf("chunk %d (rows %d-%d): %v", e.Index, e.Offset, e.Offset+e.Rows-1, e.Err)
+}
+
+// BatchInsertError is returned by Create when one or more chunks of a split insert failed,
+// chunks that are not listed were inserted successfully
+type BatchInsertError struct {
+	Chunks []ChunkError
+	// InsertedRows is the number of rows in the chunks that succeeded
+	InsertedRows int64
+}
+
+func (e *BatchInsertError) Error() string {
+	messages := make([]string, 0, len(e.Chunks))
+	for _, chunk := range e.Chunks {
+		messages = append(messages, chunk.Error())
+	}
+	return fmt.Sprintf("%d insert chunk(s) failed: %s", len(e.Chunks), strings.Join(messages, "; "))
+}
+
+// insertChunk is a single INSERT statement of a split multi-row insert
+type insertChunk struct {
+	offset int
+	rows   int
+	sql    string
+	vars   []interface{}
+}
+
+// create returns the callback replacing gorm:create, it splits multi-row inserts into chunks
+// limited by MaxInsertRows and MaxInsertBytes and leaves gormCreate the inserts which aren't split
+func (dialector Dialector) create(gormCreate func(*gorm.DB)) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		dialector.splitCreate(db, gormCreate)
+	}
+}
+
+func (dialector Dialector) splitCreate(db *gorm.DB, gormCreate func(*gorm.DB)) {
+	if db.Error != nil {
+		return
+	}
+	if db.Statement.SQL.Len() > 0 {
+		gormCreate(db)
+		return
+	}
+
+	if db.Statement.Schema != nil && !db.Statement.Unscoped {
+		for _, c := range db.Statement.Schema.CreateClauses {
+			db.Statement.AddClause(c)
+		}
+	}
+	db.Statement.AddClauseIfNotExists(clause.Insert{})
+	values := callbacks.ConvertToCreateValues(db.Statement)
+	if db.Error != nil {
+		return
+	}
+
+	split := dialector.splitValues(quotedTable(db.Statement), values)
+	if len(split) == 1 {
+		// the statement is built here with the converted values, gorm:create executes it
+		// and handles ON CONFLICT, RowsAffected and primary keys like for any other dialect
+		db.Statement.AddClause(values)
+		db.Statement.Build(db.Statement.BuildClauses...)
+		gormCreate(db)
+		return
+	}
+
+	var (
+		chunks []insertChunk
+		offset int
+	)
+	for _, rows := range split {
+		chunk := insertChunk{offset: offset, rows: len(rows)}
+		chunk.sql, chunk.vars = buildInsert(db, clause.Values{Columns: values.Columns, Values: rows})
+		chunks = append(chunks, chunk)
+		offset += len(rows)
+	}
+
+	// Statement keeps all the chunks, so the whole insert is visible in DryRun mode and logs,
+	// they are built into it again for the placeholders to be numbered across the chunks
+	for idx, rows := range split {
+		if idx > 0 {
+			db.Statement.SQL.WriteString(";\n")
+		}
+		db.Statement.AddClause(clause.Values{Columns: values.Columns, Values: rows})
+		db.Statement.Build(db.Statement.BuildClauses...)
+	}
+
+	if db.DryRun || db.Error != nil {
+		return
+	}
+	dialector.execChunks(db, chunks)
+}
+
+// execChunks executes insert chunks, running up to InsertConcurrency of them at the same time
+func (dialector Dialector) execChunks(db *gorm.DB, chunks []insertChunk) {
+	concurrency := 1
+	if dialector.Config != nil && dialector.InsertConcurrency > 1 {
+		concurrency = dialector.InsertConcurrency
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failed   []ChunkError
+		inserted int64
+		total    int64
+		limiter  = make(chan struct{}, concurrency)
+	)
+	for idx, chunk := range chunks {
+		wg.Add(1)
+		limiter <- struct{}{}
+		go func(idx int, chunk insertChunk) {
+			defer func() {
+				<-limiter
+				wg.Done()
+			}()
+
+			var affected int64
+			begin := time.Now()
+			result, err := db.Statement.ConnPool.ExecContext(db.Statement.Context, chunk.sql, chunk.vars...)
+			if err == nil {
+				affected, _ = result.RowsAffected()
+			}
+			db.Logger.Trace(db.Statement.Context, begin, func() (string, int64) {
+				return db.Dialector.Explain(chunk.sql, chunk.vars...), affected
+			}, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed = append(failed, ChunkError{Index: idx, Offset: chunk.offset, Rows: chunk.rows, Err: err})
+			} else {
+				inserted += int64(chunk.rows)
+				total += affected
+			}
+		}(idx, chunk)
+	}
+	wg.Wait()
+
+	db.RowsAffected = total
+	if len(failed) > 0 {
+		sort.Slice(failed, func(i, j int) bool { return failed[i].Index < failed[j].Index })
+		db.AddError(&BatchInsertError{Chunks: failed, InsertedRows: inserted})
+	}
+}
+
+// splitValues splits rows of the insert, so each chunk fits into the configured limits,
+// a chunk always contains at least one row
+func (dialector Dialector) splitValues(table string, values clause.Values) [][][]interface{} {
+	var maxRows, maxBytes int
+	if dialector.Config != nil {
+		maxRows, maxBytes = dialector.MaxInsertRows, dialector.MaxInsertBytes
+	}
+	if maxRows <= 0 && maxBytes <= 0 {
+		return [][][]interface{}{values.Values}
+	}
+
+	headerSize := len("INSERT INTO  () VALUES ") + len(table)
+	for _, column := range values.Columns {
+		headerSize += len(column.Name) + 3
+	}
+
+	var (
+		chunks [][][]interface{}
+		start  int
+		size   = headerSize
+	)
+	for idx, row := range values.Values {
+		rowSize := 3
+		for _, value := range row {
+			rowSize += estimateValueSize(value) + 1
+		}
+
+		rowCount := idx - start
+		if rowCount > 0 && ((maxRows > 0 && rowCount >= maxRows) || (maxBytes > 0 && size+rowSize > maxBytes)) {
+			chunks = append(chunks, values.Values[start:idx])
+			start, size = idx, headerSize
+		}
+		size += rowSize
+	}
+	return append(chunks, values.Values[start:])
+}
+
+// estimateValueSize returns the approximate length of the value once it is interpolated into the query
+func estimateValueSize(value interface{}) int {
+	switch v := value.(type) {
+	case nil:
+		return len("NULL")
+	case string:
+		return len(v) + strings.Count(v, "'") + strings.Count(v, "\\") + 2
+	case []byte:
+		return 4*len(v) + 2
+	case bool:
+		return 5
+	case time.Time:
+		return len("'2006-01-02 15:04:05.000000-07:00'")
+	case *time.Time:
+		if v == nil {
+			return len("NULL")
+		}
+		return len("'2006-01-02 15:04:05.000000-07:00'")
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return len(fmt.Sprint(v))
+	}
+	return len(fmt.Sprint(value)) + 2
+}
+
+// buildInsert builds an INSERT statement for the rows using clauses of the current statement
+func buildInsert(db *gorm.DB, values clause.Values) (string, []interface{}) {
+	stmt := &gorm.Statement{
+		DB:        db,
+		Table:     db.Statement.Table,
+		TableExpr: db.Statement.TableExpr,
+		Schema:    db.Statement.Schema,
+		Clauses:   make(map[string]clause.Clause, len(db.Statement.Clauses)),
+	}
+	for name, c := range db.Statement.Clauses {
+		stmt.Clauses[name] = c
+	}
+	stmt.AddClause(values)
+	stmt.Build(db.Statement.BuildClauses...)
+	return stmt.SQL.String(), stmt.Vars
+}
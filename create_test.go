@@ -0,0 +1,136 @@
+package firebolt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type testBatchRow struct {
+	ID   int
+	Name string
+}
+
+func testBatchRows(count int, name string) []testBatchRow {
+	rows := make([]testBatchRow, count)
+	for i := range rows {
+		rows[i] = testBatchRow{ID: i + 1, Name: name}
+	}
+	return rows
+}
+
+func TestCreateSplitByRows(t *testing.T) {
+	db := openTestDB(t, Config{MaxInsertRows: 2}, &fakeConnPool{}, &gorm.Config{DryRun: true})
+
+	rows := testBatchRows(5, "name")
+	stmt := db.Create(&rows).Statement
+	statements := strings.Split(stmt.SQL.String(), ";\n")
+	if assert.Len(t, statements, 3) {
+		assert.Equal(t, `INSERT INTO "test_batch_rows" ("name","id") VALUES (?,?),(?,?)`, statements[0])
+		assert.Equal(t, `INSERT INTO "test_batch_rows" ("name","id") VALUES (?,?)`, statements[2])
+	}
+	assert.Len(t, stmt.Vars, 10)
+}
+
+func TestCreateSplitByBytes(t *testing.T) {
+	db := openTestDB(t, Config{MaxInsertBytes: 100}, &fakeConnPool{}, &gorm.Config{DryRun: true})
+
+	rows := testBatchRows(4, strings.Repeat("x", 40))
+	statements := strings.Split(db.Create(&rows).Statement.SQL.String(), ";\n")
+	assert.Len(t, statements, 4)
+
+	// a single row bigger than the limit is still inserted
+	rows = testBatchRows(1, strings.Repeat("x", 200))
+	statements = strings.Split(db.Create(&rows).Statement.SQL.String(), ";\n")
+	assert.Len(t, statements, 1)
+
+	// the table name counts towards the limit
+	db = openTestDB(t, Config{MaxInsertBytes: 120}, &fakeConnPool{}, &gorm.Config{DryRun: true})
+	rows = testBatchRows(4, strings.Repeat("x", 20))
+	statements = strings.Split(db.Create(&rows).Statement.SQL.String(), ";\n")
+	assert.Len(t, statements, 2)
+	statements = strings.Split(db.Table("test_batch_rows_"+strings.Repeat("x", 30)).Create(&rows).Statement.SQL.String(), ";\n")
+	assert.Len(t, statements, 4)
+}
+
+func TestCreateWithoutLimits(t *testing.T) {
+	pool := &fakeConnPool{}
+	db := openTestDB(t, Config{}, pool, &gorm.Config{})
+
+	rows := testBatchRows(5, "name")
+	result := db.Create(&rows)
+	assert.NoError(t, result.Error)
+	assert.Len(t, pool.execs, 1)
+	assert.EqualValues(t, 5, result.RowsAffected)
+}
+
+func TestCreateChunkErrors(t *testing.T) {
+	pool := &fakeConnPool{}
+	var calls int
+	pool.execErr = func(query string) error {
+		calls++
+		if calls == 2 {
+			return errors.New("query is too large")
+		}
+		return nil
+	}
+	db := openTestDB(t, Config{MaxInsertRows: 2}, pool, &gorm.Config{})
+
+	rows := testBatchRows(5, "name")
+	result := db.Create(&rows)
+	assert.Len(t, pool.execs, 3)
+	assert.EqualValues(t, 3, result.RowsAffected)
+
+	var batchErr *BatchInsertError
+	if assert.True(t, errors.As(result.Error, &batchErr)) && assert.Len(t, batchErr.Chunks, 1) {
+		assert.Equal(t, ChunkError{Index: 1, Offset: 2, Rows: 2, Err: errors.New("query is too large")}, batchErr.Chunks[0])
+		assert.EqualValues(t, 3, batchErr.InsertedRows)
+	}
+}
+
+func TestCreateConcurrentChunks(t *testing.T) {
+	pool := &fakeConnPool{}
+	db := openTestDB(t, Config{MaxInsertRows: 10, InsertConcurrency: 4}, pool, &gorm.Config{})
+
+	rows := testBatchRows(95, "name")
+	result := db.Create(&rows)
+	assert.NoError(t, result.Error)
+	assert.Len(t, pool.execs, 10)
+	assert.EqualValues(t, 95, result.RowsAffected)
+}
+
+func TestCreateLogsChunks(t *testing.T) {
+	writer := &testLogWriter{}
+	db := openTestDB(t, Config{MaxInsertRows: 2}, &fakeConnPool{}, &gorm.Config{})
+	db = db.Session(&gorm.Session{Logger: logger.New(writer, logger.Config{LogLevel: logger.Info})})
+
+	rows := testBatchRows(5, "name")
+	assert.NoError(t, db.Create(&rows).Error)
+	// every chunk is logged as it is executed, then the whole insert with the total of the rows
+	if assert.Len(t, writer.lines, 4) {
+		assert.Contains(t, writer.lines[0], `[rows:2] INSERT INTO "test_batch_rows" ("name","id") VALUES ('name',1),('name',2)`)
+		assert.Contains(t, writer.lines[2], `[rows:1] INSERT INTO "test_batch_rows" ("name","id") VALUES ('name',5)`)
+		assert.Contains(t, writer.lines[3], `[rows:5] INSERT INTO "test_batch_rows" ("name","id") VALUES ('name',1),('name',2);`)
+	}
+
+	writer.lines = nil
+	rows = testBatchRows(2, "name")
+	assert.NoError(t, db.Create(&rows).Error)
+	if assert.Len(t, writer.lines, 1) {
+		assert.Contains(t, writer.lines[0], `[rows:2] INSERT INTO "test_batch_rows" ("name","id") VALUES ('name',1),('name',2)`)
+	}
+}
+
+func TestCreateInBatchesSplitByRows(t *testing.T) {
+	pool := &fakeConnPool{}
+	db := openTestDB(t, Config{MaxInsertRows: 3}, pool, &gorm.Config{})
+
+	rows := testBatchRows(10, "name")
+	assert.NoError(t, db.CreateInBatches(&rows, 5).Error)
+	// each batch of 5 rows is split into chunks of 3 and 2 rows
+	assert.Len(t, pool.execs, 4)
+}
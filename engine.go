@@ -0,0 +1,214 @@
+package firebolt
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PrimaryEngine is the name of the engine of Config.DSN
+const PrimaryEngine = "primary"
+
+// EngineRole tells which statements are routed to an engine
+type EngineRole string
+
+const (
+	// WriteEngine runs creates, updates, deletes, DDL and other statements which aren't queries
+	WriteEngine EngineRole = "write"
+	// ReadEngine runs queries
+	ReadEngine EngineRole = "read"
+)
+
+// Engine is an additional engine of the database
+type Engine struct {
+	Name string
+	DSN  string
+	Role EngineRole
+	// Conn is used instead of opening DSN
+	Conn gorm.ConnPool
+}
+
+// EnginePolicy chooses one of the engines a statement can be routed to
+type EnginePolicy interface {
+	Resolve(engines []string) string
+}
+
+// RandomPolicy chooses a random engine
+type RandomPolicy struct{}
+
+func (RandomPolicy) Resolve(engines []string) string {
+	return engines[rand.Intn(len(engines))]
+}
+
+// RoundRobinPolicy chooses the engines in turn
+type RoundRobinPolicy struct {
+	next uint64
+}
+
+func (policy *RoundRobinPolicy) Resolve(engines []string) string {
+	return engines[(atomic.AddUint64(&policy.next, 1)-1)%uint64(len(engines))]
+}
+
+const engineClauseName = "firebolt:engine"
+
+// EngineClause routes the statement to the engine of the name, regardless of its role
+type EngineClause struct {
+	Engine string
+}
+
+// UseEngine returns a clause routing the statement to the engine, e.g.
+//
+//	db.Clauses(firebolt.UseEngine("ingest")).Find(&orders)
+func UseEngine(name string) EngineClause {
+	return EngineClause{Engine: name}
+}
+
+func (engine EngineClause) ModifyStatement(stmt *gorm.Statement) {
+	stmt.Clauses[engineClauseName] = clause.Clause{Name: engineClauseName, Expression: engine}
+}
+
+func (engine EngineClause) Build(clause.Builder) {
+}
+
+// engineRouter routes statements to the engines by their kind:
+// queries go to read engines, or to write engines when there are none, the other statements go to write engines
+type engineRouter struct {
+	pools       map[string]gorm.ConnPool
+	writers     []string
+	readers     []string
+	writePolicy EnginePolicy
+	readPolicy  EnginePolicy
+
+	mu sync.Mutex
+	// prepared are the prepared statement pools of the engines for PrepareStmt sessions
+	prepared map[string]*gorm.PreparedStmtDB
+}
+
+func (dialector Dialector) registerEngineRouter(db *gorm.DB) error {
+	router := &engineRouter{
+		pools:       map[string]gorm.ConnPool{PrimaryEngine: db.ConnPool},
+		writers:     []string{PrimaryEngine},
+		writePolicy: dialector.EnginePolicy,
+		readPolicy:  dialector.EnginePolicy,
+	}
+	if dialector.EnginePolicy == nil {
+		router.writePolicy, router.readPolicy = &RoundRobinPolicy{}, &RoundRobinPolicy{}
+	}
+	for _, engine := range dialector.Engines {
+		if _, ok := router.pools[engine.Name]; ok || engine.Name == "" {
+			return fmt.Errorf("engine names must be unique and not empty, got %q", engine.Name)
+		}
+		pool := engine.Conn
+		if pool == nil {
+			pool = sql.OpenDB(dialector.connector(engine.DSN))
+		}
+		router.pools[engine.Name] = pool
+
+		switch engine.Role {
+		case WriteEngine:
+			router.writers = append(router.writers, engine.Name)
+		case ReadEngine:
+			router.readers = append(router.readers, engine.Name)
+		default:
+			return fmt.Errorf("engine %s has unknown role %q", engine.Name, engine.Role)
+		}
+	}
+	if len(router.readers) == 0 {
+		router.readers = router.writers
+	}
+
+	return registerAround(db, "firebolt:engine", func(operation string) func(*gorm.DB) {
+		return func(db *gorm.DB) {
+			router.route(db, operation)
+		}
+	}, nil)
+}
+
+func (router *engineRouter) route(db *gorm.DB, operation string) {
+	pool := db.Statement.ConnPool
+	prepared, isPrepared := pool.(*gorm.PreparedStmtDB)
+	if isPrepared {
+		pool = prepared.ConnPool
+	}
+	// statements of transactions stay on the engine the transaction began on
+	if !router.routes(pool) {
+		if _, ok := db.Statement.Clauses[engineClauseName]; ok {
+			db.Logger.Warn(db.Statement.Context, "firebolt: UseEngine is ignored, statements of transactions aren't routed")
+		}
+		return
+	}
+
+	var name string
+	if c, ok := db.Statement.Clauses[engineClauseName]; ok {
+		name = c.Expression.(EngineClause).Engine
+		if _, ok := router.pools[name]; !ok {
+			_ = db.AddError(fmt.Errorf("unknown engine %s", name))
+			return
+		}
+	} else if isRead(db, operation) {
+		name = router.readPolicy.Resolve(router.readers)
+	} else {
+		name = router.writePolicy.Resolve(router.writers)
+	}
+	if !isPrepared {
+		db.Statement.ConnPool = router.pools[name]
+	} else if prepared.ConnPool != router.pools[name] {
+		db.Statement.ConnPool = router.preparedPool(name)
+	}
+	// the name is kept for statements which have to follow on the same engine, e.g. the status of an async query
+	db.Statement.Settings.Store(engineClauseName, name)
+}
+
+// preparedPool returns the prepared statement pool of the engine, statements are prepared on every engine separately
+func (router *engineRouter) preparedPool(name string) *gorm.PreparedStmtDB {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+	if router.prepared == nil {
+		router.prepared = map[string]*gorm.PreparedStmtDB{}
+	}
+	pool, ok := router.prepared[name]
+	if !ok {
+		pool = &gorm.PreparedStmtDB{ConnPool: router.pools[name], Stmts: map[string]gorm.Stmt{}, Mux: &sync.RWMutex{}}
+		router.prepared[name] = pool
+	}
+	return pool
+}
+
+// routes tells whether the pool is one of the engines, a statement keeps its engine for the next call otherwise
+func (router *engineRouter) routes(pool gorm.ConnPool) bool {
+	for _, engine := range router.pools {
+		if engine == pool {
+			return true
+		}
+	}
+	return false
+}
+
+// isRead tells whether the statement only reads data, raw SQL is checked by its first keyword.
+// Row and Rows of a query chain have no SQL yet, gorm:row builds a SELECT for them
+func isRead(db *gorm.DB, operation string) bool {
+	switch operation {
+	case "query":
+		return true
+	case "row", "raw":
+		if db.Statement.SQL.Len() == 0 {
+			return operation == "row" && (db.Statement.Model != nil || db.Statement.Dest != nil || db.Statement.Table != "")
+		}
+		return isReadOnlySQL(db.Statement.SQL.String())
+	}
+	return false
+}
+
+// isReadOnlySQL tells whether the statement is a query by its first keyword
+func isReadOnlySQL(sql string) bool {
+	switch sqlOperation(sql) {
+	case "SELECT", "WITH", "SHOW", "DESCRIBE", "EXPLAIN":
+		return true
+	}
+	return false
+}
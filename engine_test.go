@@ -0,0 +1,140 @@
+package firebolt
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestEngineRouting(t *testing.T) {
+	primary, ingest, reader1, reader2 := &fakeDriver{}, &fakeDriver{}, &fakeDriver{}, &fakeDriver{}
+	db := openTestDB(t, Config{
+		Engines: []Engine{
+			{Name: "ingest", Role: WriteEngine, Conn: sql.OpenDB(ingest)},
+			{Name: "reader1", Role: ReadEngine, Conn: sql.OpenDB(reader1)},
+			{Name: "reader2", Role: ReadEngine, Conn: sql.OpenDB(reader2)},
+		},
+	}, sql.OpenDB(primary), &gorm.Config{})
+
+	var orders []testOrder
+	assert.NoError(t, db.Find(&orders).Error)
+	assert.NoError(t, db.Where("id = ?", 1).Find(&orders).Error)
+	assert.NoError(t, db.Raw("SELECT count(*) FROM test_orders").Scan(&[]int64{}).Error)
+	assert.NoError(t, db.Create(&testOrder{ID: 1}).Error)
+	assert.NoError(t, db.Model(&testOrder{}).Where("id = ?", 1).Update("amount", 2).Error)
+	assert.NoError(t, db.Exec("DROP TABLE test_orders").Error)
+	assert.NoError(t, db.Clauses(UseEngine("ingest")).Find(&orders).Error)
+	rows, err := db.Raw("INSERT INTO test_orders SELECT * FROM staged_orders").Rows()
+	assert.NoError(t, err)
+	rows.Close()
+
+	assert.Equal(t, []string{`SELECT * FROM "test_orders"`, "SELECT count(*) FROM test_orders"}, reader1.received())
+	assert.Equal(t, []string{`SELECT * FROM "test_orders" WHERE id = ?`}, reader2.received())
+	assert.Equal(t, []string{
+		`INSERT INTO "test_orders" ("customer","amount","id") VALUES (?,?,?)`,
+		"DROP TABLE test_orders",
+	}, primary.received())
+	assert.Equal(t, []string{
+		`UPDATE "test_orders" SET "amount"=? WHERE id = ?`,
+		`SELECT * FROM "test_orders"`,
+		"INSERT INTO test_orders SELECT * FROM staged_orders",
+	}, ingest.received())
+
+	assert.EqualError(t, db.Clauses(UseEngine("unknown")).Find(&orders).Error, "unknown engine unknown")
+
+	// a statement used for several calls is routed for each of them
+	tx := db.Model(&testOrder{}).Where("id = ?", 1)
+	assert.NoError(t, tx.Find(&orders).Error)
+	assert.NoError(t, tx.Update("amount", 3).Error)
+	assert.Equal(t, `SELECT * FROM "test_orders" WHERE id = ?`, reader2.received()[1])
+	assert.Equal(t, "DROP TABLE test_orders", primary.received()[1])
+	assert.Equal(t, `UPDATE "test_orders" SET "amount"=? WHERE id = ?`, primary.received()[2])
+}
+
+func TestEngineRoutingRows(t *testing.T) {
+	primary, reader := &fakeDriver{}, &fakeDriver{}
+	db := openTestDB(t, Config{
+		Engines: []Engine{{Name: "reader", Role: ReadEngine, Conn: sql.OpenDB(reader)}},
+	}, sql.OpenDB(primary), &gorm.Config{})
+
+	// Row and Rows of a query chain are built by gorm:row after routing, they are queries
+	rows, err := db.Model(&testOrder{}).Where("id = ?", 1).Rows()
+	if assert.NoError(t, err) {
+		rows.Close()
+	}
+	rows, err = db.Table("test_orders").Select("id").Rows()
+	if assert.NoError(t, err) {
+		rows.Close()
+	}
+	assert.NoError(t, db.Model(&testOrder{}).Select("count(*)").Row().Err())
+	rows, err = db.Raw("INSERT INTO test_orders SELECT * FROM staged_orders").Rows()
+	if assert.NoError(t, err) {
+		rows.Close()
+	}
+
+	assert.Equal(t, []string{
+		`SELECT * FROM "test_orders" WHERE id = ?`,
+		`SELECT id FROM "test_orders"`,
+		`SELECT count(*) FROM "test_orders"`,
+	}, reader.received())
+	assert.Equal(t, []string{"INSERT INTO test_orders SELECT * FROM staged_orders"}, primary.received())
+}
+
+func TestEngineRoutingPreparedStatements(t *testing.T) {
+	primary, reader := &fakeDriver{}, &fakeDriver{}
+	db := openTestDB(t, Config{
+		Engines: []Engine{{Name: "reader", Role: ReadEngine, Conn: sql.OpenDB(reader)}},
+	}, sql.OpenDB(primary), &gorm.Config{PrepareStmt: true})
+
+	var orders []testOrder
+	assert.NoError(t, db.Find(&orders).Error)
+	assert.NoError(t, db.Find(&orders).Error)
+	assert.NoError(t, db.Create(&testOrder{ID: 1}).Error)
+	assert.NoError(t, db.Clauses(UseEngine(PrimaryEngine)).Find(&orders).Error)
+
+	// statements are prepared once on every engine they are routed to
+	assert.Equal(t, []string{`SELECT * FROM "test_orders"`}, reader.prepared)
+	assert.Equal(t, []string{`SELECT * FROM "test_orders"`, `SELECT * FROM "test_orders"`}, reader.received())
+	assert.Equal(t, []string{`INSERT INTO "test_orders" ("customer","amount","id") VALUES (?,?,?)`, `SELECT * FROM "test_orders"`}, primary.prepared)
+	assert.Equal(t, primary.prepared, primary.received())
+}
+
+func TestEngineRoutingTransactions(t *testing.T) {
+	primary, reader := &fakeDriver{}, &fakeDriver{}
+	writer := &testLogWriter{}
+	db := openTestDB(t, Config{
+		Transactions: true,
+		Engines:      []Engine{{Name: "reader", Role: ReadEngine, Conn: sql.OpenDB(reader)}},
+	}, sql.OpenDB(fakeTxDriver{primary}), &gorm.Config{})
+	db = db.Session(&gorm.Session{Logger: logger.New(writer, logger.Config{LogLevel: logger.Warn})})
+
+	// statements of a transaction stay on the engine it began on, UseEngine is ignored with a warning
+	assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return tx.Clauses(UseEngine("reader")).Find(&[]testOrder{}).Error
+	}))
+	assert.Equal(t, []string{"BEGIN", `SELECT * FROM "test_orders"`, "COMMIT"}, primary.received())
+	assert.Empty(t, reader.received())
+	if assert.Len(t, writer.lines, 1) {
+		assert.Contains(t, writer.lines[0], "UseEngine is ignored")
+	}
+}
+
+func TestEngineRoutingWithoutReaders(t *testing.T) {
+	primary, ingest := &fakeDriver{}, &fakeDriver{}
+	db := openTestDB(t, Config{
+		Engines:      []Engine{{Name: "ingest", Role: WriteEngine, Conn: sql.OpenDB(ingest)}},
+		EnginePolicy: RandomPolicy{},
+	}, sql.OpenDB(primary), &gorm.Config{})
+
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, db.Find(&[]testOrder{}).Error)
+	}
+	assert.Len(t, append(primary.received(), ingest.received()...), 20)
+
+	_, err := gorm.Open(&Dialector{Config: &Config{Engines: []Engine{{Name: PrimaryEngine, Role: ReadEngine}}}, Conn: sql.OpenDB(primary)},
+		&gorm.Config{DisableAutomaticPing: true, Logger: logger.Discard})
+	assert.EqualError(t, err, `engine names must be unique and not empty, got "primary"`)
+}
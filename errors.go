@@ -0,0 +1,94 @@
+package firebolt
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrorClass is a coarse category of an error returned by Firebolt or the SDK
+type ErrorClass string
+
+const (
+	ErrorClassNone       ErrorClass = ""
+	ErrorClassNotFound   ErrorClass = "not_found"
+	ErrorClassCanceled   ErrorClass = "canceled"
+	ErrorClassTimeout    ErrorClass = "timeout"
+	ErrorClassConnection ErrorClass = "connection"
+	ErrorClassAuth       ErrorClass = "auth"
+	ErrorClassSyntax     ErrorClass = "syntax"
+	ErrorClassClient     ErrorClass = "client"
+	ErrorClassServer     ErrorClass = "server"
+	ErrorClassOther      ErrorClass = "other"
+)
+
+var (
+	statusCodePattern = regexp.MustCompile(`status code: (\d{3})`)
+	errorCodePattern  = regexp.MustCompile(`Code: (\d+)\.`)
+)
+
+// Firebolt error codes of syntax and analysis errors
+var syntaxErrorCodes = map[int]bool{47: true, 60: true, 62: true, 81: true}
+
+// StatusCode returns HTTP status code of the failed Firebolt request, 0 when the error doesn't carry it
+func StatusCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if match := statusCodePattern.FindStringSubmatch(err.Error()); match != nil {
+		code, _ := strconv.Atoi(match[1])
+		return code
+	}
+	return 0
+}
+
+// ErrorCode returns Firebolt error code of a database error, e.g. 62 for syntax errors, 0 when the error doesn't carry it
+func ErrorCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if match := errorCodePattern.FindStringSubmatch(err.Error()); match != nil {
+		code, _ := strconv.Atoi(match[1])
+		return code
+	}
+	return 0
+}
+
+// ClassifyError returns the class of the error, the SDK reports errors as text,
+// so the class is derived from the HTTP status code, the Firebolt error code and the message
+func ClassifyError(err error) ErrorClass {
+	switch {
+	case err == nil:
+		return ErrorClassNone
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrorClassNotFound
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	}
+
+	message := err.Error()
+	status := StatusCode(err)
+	switch {
+	case strings.Contains(message, "context canceled"):
+		return ErrorClassCanceled
+	case strings.Contains(message, "deadline exceeded"), status == 408, status == 504:
+		return ErrorClassTimeout
+	case status == 401, status == 403, strings.Contains(message, "access token"), strings.Contains(message, "authentication"):
+		return ErrorClassAuth
+	case strings.Contains(message, "error during a request execution"), status == 502, status == 503:
+		return ErrorClassConnection
+	case syntaxErrorCodes[ErrorCode(err)], strings.Contains(message, "Syntax error"):
+		return ErrorClassSyntax
+	case status >= 400 && status < 500:
+		return ErrorClassClient
+	case status >= 500, strings.Contains(message, "DB::Exception"):
+		return ErrorClassServer
+	}
+	return ErrorClassOther
+}
@@ -0,0 +1,37 @@
+package firebolt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestClassifyError(t *testing.T) {
+	for _, test := range []struct {
+		err   error
+		class ErrorClass
+	}{
+		{nil, ErrorClassNone},
+		{gorm.ErrRecordNotFound, ErrorClassNotFound},
+		{fmt.Errorf("query: %w", context.Canceled), ErrorClassCanceled},
+		{context.DeadlineExceeded, ErrorClassTimeout},
+		{errors.New("error during query request: error during a request execution: dial tcp: connection refused"), ErrorClassConnection},
+		{errors.New("error during query request: request returned non ok status code: 503, unavailable"), ErrorClassConnection},
+		{errors.New("error during query request: request returned non ok status code: 401, unauthorized"), ErrorClassAuth},
+		{errors.New("error during query execution: Code: 62. DB::Exception: Syntax error: failed at position 8"), ErrorClassSyntax},
+		{errors.New("error during query request: request returned non ok status code: 429, too many requests"), ErrorClassClient},
+		{errors.New("error during query execution: Code: 241. DB::Exception: Memory limit exceeded"), ErrorClassServer},
+		{errors.New("multistatement is not allowed"), ErrorClassOther},
+	} {
+		assert.Equal(t, test.class, ClassifyError(test.err), "%v", test.err)
+	}
+
+	err := errors.New("request returned non ok status code: 500, Code: 241. DB::Exception: Memory limit exceeded")
+	assert.Equal(t, 500, StatusCode(err))
+	assert.Equal(t, 241, ErrorCode(err))
+	assert.Equal(t, 0, StatusCode(errors.New("multistatement is not allowed")))
+}
@@ -0,0 +1,230 @@
+package firebolt
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Plan is a query plan returned by EXPLAIN
+type Plan struct {
+	// Root is the topmost operator of the plan
+	Root *PlanNode
+	// Text is EXPLAIN output as returned by the server
+	Text string
+}
+
+// PlanNode is an operator of a query plan, e.g.
+//
+//	\_[2] [StoredTable] Name: "orders", used 2/5 column(s) FACT
+type PlanNode struct {
+	ID int
+	// Operator is the operator kind, e.g. Projection, Aggregate, StoredTable
+	Operator string
+	// Details is the rest of the operator line
+	Details string
+	// Table is the table or the index read by StoredTable operator
+	Table string
+	// Properties are "[Name]: value" lines of the operator, e.g. RowType or Execution Metrics
+	Properties map[string]string
+	// Metrics are "name = value" pairs of the properties
+	Metrics map[string]string
+	// EstimatedRows is the estimated number of output rows, -1 when the server doesn't report it
+	EstimatedRows int64
+	// Rows is the number of output rows reported by EXPLAIN (ANALYZE), -1 for plain EXPLAIN
+	Rows     int64
+	Children []*PlanNode
+}
+
+// Explain returns the plan of the query built by queryFn, e.g.
+//
+//	plan, err := firebolt.Explain(db, func(tx *gorm.DB) *gorm.DB {
+//		return tx.Where("amount > ?", 100).Find(&orders)
+//	})
+//
+// Like gorm.DB.ToSQL, queryFn gets a DryRun session, so the statement is only built and never executed itself
+func Explain(db *gorm.DB, queryFn func(tx *gorm.DB) *gorm.DB) (*Plan, error) {
+	return explain(db, queryFn, "EXPLAIN ")
+}
+
+// ExplainAnalyze executes the query built by queryFn as a part of EXPLAIN (ANALYZE) and returns its plan with
+// execution metrics, a statement which modifies data modifies it
+func ExplainAnalyze(db *gorm.DB, queryFn func(tx *gorm.DB) *gorm.DB) (*Plan, error) {
+	return explain(db, queryFn, "EXPLAIN (ANALYZE) ")
+}
+
+func explain(db *gorm.DB, queryFn func(tx *gorm.DB) *gorm.DB, prefix string) (*Plan, error) {
+	// only the EXPLAIN statement is logged
+	query := queryFn(db.Session(&gorm.Session{DryRun: true, SkipDefaultTransaction: true, Logger: logger.Discard}))
+	if query.Error != nil {
+		return nil, query.Error
+	}
+	stmt := query.Statement
+	if stmt.SQL.Len() == 0 {
+		return nil, errors.New("Explain requires a query built by a finisher method, e.g. Find")
+	}
+
+	sql := prefix + stmt.SQL.String()
+	begin := time.Now()
+	rows, err := stmt.ConnPool.QueryContext(stmt.Context, sql, stmt.Vars...)
+	db.Logger.Trace(stmt.Context, begin, func() (string, int64) {
+		return db.Dialector.Explain(sql, stmt.Vars...), -1
+	}, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("unexpected EXPLAIN output, no columns returned")
+	}
+	var lines []string
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		var line string
+		values[0] = &line
+		for idx := 1; idx < len(values); idx++ {
+			values[idx] = new(interface{})
+		}
+		if err = rows.Scan(values...); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return ParsePlan(strings.Join(lines, "\n"))
+}
+
+var (
+	planNodeLine     = regexp.MustCompile(`^([\s|]*(?:\\_)?)\[(\d+)\]\s*\[([^\]]+)\]\s*(.*)$`)
+	planPropertyLine = regexp.MustCompile(`^\[([^\]]+)\]:\s*(.*)$`)
+	planTableName    = regexp.MustCompile(`Name:\s*["']([^"']+)["']`)
+	planMetric       = regexp.MustCompile(`([a-zA-Z][\w ]*?)\s*=\s*([^,]+)`)
+)
+
+// ParsePlan parses the text output of EXPLAIN, operators are nested by their indentation
+func ParsePlan(text string) (*Plan, error) {
+	type level struct {
+		depth int
+		node  *PlanNode
+	}
+	var (
+		plan  = &Plan{Text: text}
+		stack []level
+		last  *PlanNode
+	)
+
+	for _, line := range strings.Split(text, "\n") {
+		if match := planNodeLine.FindStringSubmatch(line); match != nil {
+			id, _ := strconv.Atoi(match[2])
+			node := &PlanNode{
+				ID:            id,
+				Operator:      match[3],
+				Details:       strings.TrimSpace(match[4]),
+				Properties:    map[string]string{},
+				Metrics:       map[string]string{},
+				EstimatedRows: -1,
+				Rows:          -1,
+			}
+			if table := planTableName.FindStringSubmatch(node.Details); table != nil {
+				node.Table = table[1]
+			}
+
+			depth := len(match[1])
+			for len(stack) > 0 && stack[len(stack)-1].depth >= depth {
+				stack = stack[:len(stack)-1]
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1].node
+				parent.Children = append(parent.Children, node)
+			} else if plan.Root == nil {
+				plan.Root = node
+			} else {
+				return nil, fmt.Errorf("unexpected EXPLAIN output, operator [%d] has no parent", id)
+			}
+			stack = append(stack, level{depth: depth, node: node})
+			last = node
+			continue
+		}
+
+		property := planPropertyLine.FindStringSubmatch(strings.TrimLeft(line, " \t|"))
+		if property == nil || last == nil {
+			continue
+		}
+		last.Properties[property[1]] = property[2]
+		for _, metric := range planMetric.FindAllStringSubmatch(property[2], -1) {
+			last.Metrics[strings.TrimSpace(metric[1])] = strings.TrimSpace(metric[2])
+		}
+		if rows, err := strconv.ParseInt(last.Metrics["output cardinality"], 10, 64); err == nil {
+			last.Rows = rows
+		}
+		for _, name := range []string{"estimated cardinality", "estimated rows"} {
+			if rows, err := strconv.ParseInt(last.Metrics[name], 10, 64); err == nil {
+				last.EstimatedRows = rows
+			}
+		}
+	}
+
+	if plan.Root == nil {
+		return nil, errors.New("unexpected EXPLAIN output, no operators found")
+	}
+	return plan, nil
+}
+
+// Walk calls fn for every operator of the plan, parents before their children
+func (plan *Plan) Walk(fn func(node *PlanNode)) {
+	var walk func(node *PlanNode)
+	walk = func(node *PlanNode) {
+		fn(node)
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	if plan.Root != nil {
+		walk(plan.Root)
+	}
+}
+
+// Find returns operators of the kind, e.g. Aggregate
+func (plan *Plan) Find(operator string) []*PlanNode {
+	var nodes []*PlanNode
+	plan.Walk(func(node *PlanNode) {
+		if node.Operator == operator {
+			nodes = append(nodes, node)
+		}
+	})
+	return nodes
+}
+
+// Tables returns the tables and indexes read by the plan
+func (plan *Plan) Tables() []string {
+	var tables []string
+	plan.Walk(func(node *PlanNode) {
+		if node.Table != "" {
+			tables = append(tables, node.Table)
+		}
+	})
+	return tables
+}
+
+// UsesIndex reports whether the plan reads the index, e.g. an aggregating index the query is answered from
+func (plan *Plan) UsesIndex(name string) bool {
+	for _, table := range plan.Tables() {
+		if table == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package firebolt
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+const testAnalyzedPlan = `[0] [Projection] country, sum_0
+|   [RowType]: text not null, bigint null
+|   [Execution Metrics]: output cardinality = 3, thread time = 1ms, cpu time = 1ms
+ \_[1] [Aggregate] GroupBy: [country] Aggregates: [sum_0: sum(amount)]
+    |   [Execution Metrics]: output cardinality = 3, estimated cardinality = 4, thread time = 2ms, cpu time = 2ms
+    \_[2] [Filter] (amount > 100)
+       |   [Execution Metrics]: output cardinality = 120, thread time = 0ms, cpu time = 0ms
+       \_[3] [StoredTable] Name: "orders_agg_idx", used 2/5 column(s) AGGREGATING INDEX
+          |   [Execution Metrics]: output cardinality = 250, thread time = 4ms, cpu time = 3ms`
+
+func TestParsePlan(t *testing.T) {
+	plan, err := ParsePlan(testAnalyzedPlan)
+	assert.NoError(t, err)
+
+	root := plan.Root
+	assert.Equal(t, "Projection", root.Operator)
+	assert.Equal(t, "country, sum_0", root.Details)
+	assert.Equal(t, "text not null, bigint null", root.Properties["RowType"])
+	assert.Equal(t, int64(3), root.Rows)
+	assert.Equal(t, int64(-1), root.EstimatedRows)
+
+	aggregates := plan.Find("Aggregate")
+	if assert.Len(t, aggregates, 1) {
+		assert.Equal(t, 1, aggregates[0].ID)
+		assert.Equal(t, int64(4), aggregates[0].EstimatedRows)
+		assert.Equal(t, "2ms", aggregates[0].Metrics["thread time"])
+	}
+
+	var operators []string
+	plan.Walk(func(node *PlanNode) {
+		operators = append(operators, node.Operator)
+	})
+	assert.Equal(t, []string{"Projection", "Aggregate", "Filter", "StoredTable"}, operators)
+	assert.Equal(t, []string{"orders_agg_idx"}, plan.Tables())
+	assert.True(t, plan.UsesIndex("orders_agg_idx"))
+	assert.False(t, plan.UsesIndex("orders"))
+	assert.Equal(t, int64(250), plan.Find("StoredTable")[0].Rows)
+}
+
+func TestParsePlanSiblings(t *testing.T) {
+	plan, err := ParsePlan(`[0] [Projection] o.id, c.name
+ \_[1] [Join] Mode: Inner [(o.country = c.code)]
+    \_[2] [StoredTable] Name: 'orders', used 2/5 column(s) FACT
+    \_[3] [StoredTable] Name: 'countries', used 2/2 column(s) DIMENSION`)
+	assert.NoError(t, err)
+
+	join := plan.Root.Children[0]
+	if assert.Len(t, join.Children, 2) {
+		assert.Equal(t, "orders", join.Children[0].Table)
+		assert.Equal(t, "countries", join.Children[1].Table)
+		assert.Equal(t, int64(-1), join.Children[0].Rows)
+	}
+
+	_, err = ParsePlan("")
+	assert.Error(t, err)
+}
+
+func TestExplain(t *testing.T) {
+	db, fake := openFakeDB(t, Config{}, func(query string, args []driver.NamedValue) (*fakeResult, error) {
+		if !strings.HasPrefix(query, "EXPLAIN") {
+			return nil, nil
+		}
+		result := &fakeResult{columns: []string{"explain"}}
+		for _, line := range strings.Split(testAnalyzedPlan, "\n") {
+			result.rows = append(result.rows, []driver.Value{line})
+		}
+		return result, nil
+	})
+
+	var orders []testOrder
+	query := func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("amount > ?", 100).Find(&orders)
+	}
+	plan, err := Explain(db, query)
+	assert.NoError(t, err)
+	assert.True(t, plan.UsesIndex("orders_agg_idx"))
+	assert.Equal(t, testAnalyzedPlan, plan.Text)
+
+	_, err = ExplainAnalyze(db, query)
+	assert.NoError(t, err)
+
+	// statements which modify data are only built
+	_, err = Explain(db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&testOrder{}).Where("id = ?", 1).Update("amount", 5)
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`EXPLAIN SELECT * FROM "test_orders" WHERE amount > ?`,
+		`EXPLAIN (ANALYZE) SELECT * FROM "test_orders" WHERE amount > ?`,
+		`EXPLAIN UPDATE "test_orders" SET "amount"=? WHERE id = ?`,
+	}, fake.received())
+	assert.Equal(t, []driver.NamedValue{{Ordinal: 1, Value: int64(100)}}, fake.args[0])
+
+	_, err = Explain(db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("amount > ?", 100)
+	})
+	assert.Error(t, err)
+
+	fake.handler = nil
+	_, err = Explain(db, query)
+	assert.EqualError(t, err, "unexpected EXPLAIN output, no columns returned")
+}
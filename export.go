@@ -0,0 +1,290 @@
+package firebolt
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ExportFormat is a file format of Export
+type ExportFormat string
+
+const (
+	ExportCSV     ExportFormat = "CSV"
+	ExportJSONL   ExportFormat = "JSONL"
+	ExportParquet ExportFormat = "PARQUET"
+)
+
+// ExportBatchSize is the number of rows Export reads with a query when it pages on the primary index
+var ExportBatchSize = 100000
+
+// Export writes the result of the query to w, e.g.
+//
+//	firebolt.Export(db.Model(&Order{}).Where("day >= ?", day), file, firebolt.ExportParquet)
+//
+// Columns are named and typed after the fields of the model, columns the model doesn't have, e.g. selected
+// expressions, are typed after the driver's column types. CSV has a header row, JSON Lines has an object per row.
+// Rows of a model with a primary index are read in batches of ExportBatchSize like by Iterate, unless the query
+// groups, orders or limits them or doesn't select the primary index. RowsAffected of the result is the number of exported rows
+func Export(db *gorm.DB, w io.Writer, format ExportFormat) *gorm.DB {
+	tx := db.Session(&gorm.Session{})
+	switch format {
+	case ExportCSV, ExportJSONL, ExportParquet:
+	default:
+		_ = tx.AddError(fmt.Errorf("unsupported export format %s", format))
+		return tx
+	}
+
+	var s *schema.Schema
+	if model := db.Statement.Model; model != nil {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(model); err == nil {
+			s = stmt.Schema
+		}
+	}
+	primaryFields := exportPrimaryFields(db.Statement, s)
+
+	var (
+		writer  *exportWriter
+		key     []interface{}
+		indexes []int
+	)
+	for {
+		query := tx
+		if len(primaryFields) > 0 {
+			query = tx.Clauses(clause.OrderBy{Columns: primaryIndexOrder(primaryFields)}).Limit(ExportBatchSize)
+			if key != nil {
+				query = query.Where(keysetCondition(primaryFields, key))
+			}
+		}
+		rows, err := query.Rows()
+		if err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+		if writer == nil {
+			if writer, err = newExportWriter(rows, s, w, format); err == nil {
+				indexes, err = writer.indexesOf(primaryFields)
+			}
+		}
+		var count int
+		if err == nil {
+			count, err = writer.writeRows(rows, func(values []interface{}) {
+				key = make([]interface{}, len(indexes))
+				for idx, column := range indexes {
+					key[idx], _ = driverValue(values[column])
+				}
+			})
+		}
+		if closeErr := rows.Close(); err == nil {
+			err = closeErr
+		}
+		tx.RowsAffected += int64(count)
+		if err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+		if len(primaryFields) == 0 || count < ExportBatchSize {
+			_ = tx.AddError(writer.close())
+			return tx
+		}
+	}
+}
+
+// exportPrimaryFields returns the primary fields to page on, nil when the statement can't be paged
+func exportPrimaryFields(stmt *gorm.Statement, s *schema.Schema) []*schema.Field {
+	if s == nil || len(s.PrimaryFields) == 0 || stmt.Distinct || stmt.SQL.Len() > 0 {
+		return nil
+	}
+	for _, name := range []string{"GROUP BY", "ORDER BY", "LIMIT"} {
+		if _, ok := stmt.Clauses[name]; ok {
+			return nil
+		}
+	}
+	if len(stmt.Selects) > 0 {
+		for _, field := range s.PrimaryFields {
+			selected := false
+			for _, name := range stmt.Selects {
+				selected = selected || name == field.DBName || name == field.Name
+			}
+			if !selected {
+				return nil
+			}
+		}
+	}
+	return s.PrimaryFields
+}
+
+// primaryIndexOrder orders the rows by the primary index
+func primaryIndexOrder(fields []*schema.Field) []clause.OrderByColumn {
+	order := make([]clause.OrderByColumn, 0, len(fields))
+	for _, field := range fields {
+		order = append(order, clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}})
+	}
+	return order
+}
+
+// exportWriter writes the scanned rows in the format of the export
+type exportWriter struct {
+	fields   []*schema.Field
+	dests    []interface{}
+	writeRow func(values []interface{}) error
+	close    func() error
+}
+
+// newExportWriter types the columns of the rows after the fields of the schema, or the column types of the driver
+func newExportWriter(rows *sql.Rows, s *schema.Schema, w io.Writer, format ExportFormat) (*exportWriter, error) {
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	writer := &exportWriter{fields: make([]*schema.Field, len(columnTypes)), dests: make([]interface{}, len(columnTypes))}
+	for idx, columnType := range columnTypes {
+		if s != nil {
+			if field := s.LookUpField(columnType.Name()); field != nil && field.DBName == columnType.Name() {
+				writer.fields[idx] = field
+				// a pointer to the field type scans NULL as nil
+				if field.FieldType.Kind() == reflect.Ptr {
+					writer.dests[idx] = reflect.New(field.FieldType).Interface()
+				} else {
+					writer.dests[idx] = reflect.New(reflect.PtrTo(field.FieldType)).Interface()
+				}
+				continue
+			}
+		}
+		writer.fields[idx] = &schema.Field{DBName: columnType.Name(), DataType: dataTypeOfScanType(columnType.ScanType())}
+		writer.dests[idx] = new(interface{})
+	}
+
+	switch format {
+	case ExportParquet:
+		columns := make([]*parquetColumn, 0, len(writer.fields))
+		for _, field := range writer.fields {
+			columns = append(columns, parquetColumnOf(field))
+		}
+		pw, err := newParquetWriter(w, columns)
+		if err != nil {
+			return nil, err
+		}
+		writer.writeRow, writer.close = pw.WriteRow, pw.Close
+	case ExportJSONL:
+		writer.writeRow, writer.close = newJSONLWriter(w, writer.fields)
+	default:
+		if writer.writeRow, writer.close, err = newCSVWriter(w, writer.fields); err != nil {
+			return nil, err
+		}
+	}
+	return writer, nil
+}
+
+// indexesOf returns the indexes of the columns of the fields
+func (writer *exportWriter) indexesOf(fields []*schema.Field) ([]int, error) {
+	indexes := make([]int, 0, len(fields))
+	for _, field := range fields {
+		index := -1
+		for idx, column := range writer.fields {
+			if column.DBName == field.DBName {
+				index = idx
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("the result of the export has no %s column to page on", field.DBName)
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, nil
+}
+
+// writeRows writes the rows and returns their number, written is called with the values of every written row
+func (writer *exportWriter) writeRows(rows *sql.Rows, written func(values []interface{})) (int, error) {
+	var count int
+	values := make([]interface{}, len(writer.dests))
+	for rows.Next() {
+		if err := rows.Scan(writer.dests...); err != nil {
+			return count, err
+		}
+		for idx, dest := range writer.dests {
+			values[idx] = reflect.ValueOf(dest).Elem().Interface()
+		}
+		if err := writer.writeRow(values); err != nil {
+			return count, err
+		}
+		written(values)
+		count++
+	}
+	return count, rows.Err()
+}
+
+// dataTypeOfScanType maps the type the driver scans a column into to the data type of a field
+func dataTypeOfScanType(scanType reflect.Type) schema.DataType {
+	if scanType == nil {
+		return schema.String
+	}
+	for scanType.Kind() == reflect.Ptr {
+		scanType = scanType.Elem()
+	}
+	switch {
+	case scanType == reflect.TypeOf(time.Time{}):
+		return schema.Time
+	case scanType.Kind() == reflect.Slice && scanType.Elem().Kind() == reflect.Uint8:
+		return schema.Bytes
+	}
+	switch scanType.Kind() {
+	case reflect.Bool:
+		return schema.Bool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return schema.Int
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return schema.Uint
+	case reflect.Float32, reflect.Float64:
+		return schema.Float
+	}
+	return schema.String
+}
+
+// newJSONLWriter returns functions writing a row as a JSON object with keys in the column order and flushing the writer
+func newJSONLWriter(w io.Writer, fields []*schema.Field) (writeRow func(values []interface{}) error, flush func() error) {
+	writer := bufio.NewWriter(w)
+	keys := make([][]byte, len(fields))
+	for idx, field := range fields {
+		keys[idx], _ = json.Marshal(field.DBName)
+	}
+
+	var line bytes.Buffer
+	writeRow = func(values []interface{}) error {
+		line.Reset()
+		line.WriteByte('{')
+		for idx, value := range values {
+			value, err := driverValue(value)
+			if err != nil {
+				return fmt.Errorf("column %s: %w", fields[idx].DBName, err)
+			}
+			data, err := json.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("column %s: %w", fields[idx].DBName, err)
+			}
+			if idx > 0 {
+				line.WriteByte(',')
+			}
+			line.Write(keys[idx])
+			line.WriteByte(':')
+			line.Write(data)
+		}
+		line.WriteString("}\n")
+		_, err := writer.Write(line.Bytes())
+		return err
+	}
+	flush = func() error {
+		return writer.Flush()
+	}
+	return writeRow, flush
+}
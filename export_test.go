@@ -0,0 +1,103 @@
+package firebolt
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExport(t *testing.T) {
+	day := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+	db, fake := openFakeDB(t, Config{}, func(query string, args []driver.NamedValue) (*fakeResult, error) {
+		return &fakeResult{
+			columns: []string{"day", "country", "revenue", "orders"},
+			rows: [][]driver.Value{
+				{day, "de", 1.5, int64(3)},
+				{day, `fr, "south"`, nil, int64(1)},
+			},
+		}, nil
+	})
+	query := db.Model(&testDailyRevenue{}).Select("day, country, revenue, count(*) AS orders").Group("day, country, revenue")
+
+	var csv bytes.Buffer
+	result := Export(query, &csv, ExportCSV)
+	assert.NoError(t, result.Error)
+	assert.Equal(t, int64(2), result.RowsAffected)
+	assert.Equal(t, "day,country,revenue,orders\n"+
+		"2022-08-01 00:00:00+00:00,de,1.5,3\n"+
+		"2022-08-01 00:00:00+00:00,\"fr, \"\"south\"\"\",,1\n", csv.String())
+	assert.Equal(t, `SELECT day, country, revenue, count(*) AS orders FROM "test_daily_revenues" GROUP BY day, country, revenue`, fake.received()[0])
+
+	var jsonl bytes.Buffer
+	assert.NoError(t, Export(query, &jsonl, ExportJSONL).Error)
+	assert.Equal(t, `{"day":"2022-08-01T00:00:00Z","country":"de","revenue":1.5,"orders":3}`+"\n"+
+		`{"day":"2022-08-01T00:00:00Z","country":"fr, \"south\"","revenue":null,"orders":1}`+"\n", jsonl.String())
+
+	var parquet bytes.Buffer
+	result = Export(query, &parquet, ExportParquet)
+	assert.NoError(t, result.Error)
+	// revenue keeps the type of the model's field and is stored as a double, orders isn't a field and is stored as a string
+	file := readParquet(t, parquet.Bytes())
+	assert.Equal(t, []string{"day INT64 OPTIONAL TIMESTAMP_MICROS", "country BYTE_ARRAY OPTIONAL UTF8", "revenue DOUBLE OPTIONAL", "orders BYTE_ARRAY OPTIONAL UTF8"}, file.schema)
+	assert.Equal(t, [][]interface{}{
+		{day.UnixMicro(), "de", 1.5, "3"},
+		{day.UnixMicro(), `fr, "south"`, nil, "1"},
+	}, file.rows)
+
+	assert.EqualError(t, Export(query, &csv, "XML").Error, "unsupported export format XML")
+}
+
+func TestExportPages(t *testing.T) {
+	batchSize := ExportBatchSize
+	ExportBatchSize = 2
+	t.Cleanup(func() { ExportBatchSize = batchSize })
+
+	day := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+	pages := [][][]driver.Value{
+		{{day, "de", 1.5}, {day, "fr", 2.5}},
+		{{day, "it", 3.5}, {day.AddDate(0, 0, 1), "de", 4.5}},
+		{},
+	}
+	db, fake := openFakeDB(t, Config{}, func(query string, args []driver.NamedValue) (*fakeResult, error) {
+		page := pages[0]
+		pages = pages[1:]
+		return &fakeResult{columns: []string{"day", "country", "revenue"}, rows: page}, nil
+	})
+
+	var csv bytes.Buffer
+	result := Export(db.Model(&testDailyRevenue{}).Where("revenue > ?", 1), &csv, ExportCSV)
+	assert.NoError(t, result.Error)
+	assert.Equal(t, int64(4), result.RowsAffected)
+	assert.Equal(t, "day,country,revenue\n"+
+		"2022-08-01 00:00:00+00:00,de,1.5\n"+
+		"2022-08-01 00:00:00+00:00,fr,2.5\n"+
+		"2022-08-01 00:00:00+00:00,it,3.5\n"+
+		"2022-08-02 00:00:00+00:00,de,4.5\n", csv.String())
+
+	// every page continues after the primary index values of the last row
+	after := `SELECT * FROM "test_daily_revenues" WHERE revenue > ? AND ("test_daily_revenues"."day" >= ? AND ` +
+		`("test_daily_revenues"."day" > ? OR ("test_daily_revenues"."day" = ? AND "test_daily_revenues"."country" > ?))) ` +
+		`ORDER BY "test_daily_revenues"."day","test_daily_revenues"."country" LIMIT 2`
+	assert.Equal(t, []string{
+		`SELECT * FROM "test_daily_revenues" WHERE revenue > ? ORDER BY "test_daily_revenues"."day","test_daily_revenues"."country" LIMIT 2`,
+		after,
+		after,
+	}, fake.received())
+	assert.Equal(t, []driver.NamedValue{
+		{Ordinal: 1, Value: int64(1)}, {Ordinal: 2, Value: day}, {Ordinal: 3, Value: day}, {Ordinal: 4, Value: day}, {Ordinal: 5, Value: "fr"},
+	}, fake.args[1])
+	assert.Equal(t, "de", fake.args[2][4].Value)
+}
+
+func TestExportErrorKeepsDB(t *testing.T) {
+	db, fake := openFakeDB(t, Config{}, nil)
+
+	assert.EqualError(t, Export(db, io.Discard, "XML").Error, "unsupported export format XML")
+	assert.NoError(t, db.Error)
+	assert.NoError(t, db.Find(&[]testDailyRevenue{}).Error)
+	assert.Equal(t, []string{`SELECT * FROM "test_daily_revenues"`}, fake.received())
+}
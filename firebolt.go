@@ -1,12 +1,16 @@
 package firebolt
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
-	_ "github.com/firebolt-db/firebolt-go-sdk"
+	fireboltgosdk "github.com/firebolt-db/firebolt-go-sdk"
 	"gorm.io/gorm"
 
 	"gorm.io/gorm/callbacks"
@@ -18,6 +22,35 @@ import (
 
 type Config struct {
 	DSN string
+	// MaxInsertRows limits the number of rows in a single INSERT statement, 0 means no limit
+	MaxInsertRows int
+	// MaxInsertBytes limits the estimated size of a single INSERT statement, 0 means no limit
+	MaxInsertBytes int
+	// InsertConcurrency is the number of INSERT statements of a split insert executed at the same time
+	InsertConcurrency int
+	// PositionalParameters writes statement parameters as $1, $2... placeholders instead of ?.
+	// Initialize checks that the connection binds them with SELECT $1 and falls back to ? placeholders otherwise
+	PositionalParameters bool
+	// CancelQueries cancels statements on the engine with CANCEL QUERY when their context is done,
+	// every statement is sent with a generated query_id setting to be cancelled by
+	CancelQueries bool
+	// Transactions begins transactions with the driver of Dialector.Conn, which has to carry them between statements,
+	// Initialize fails with ErrTransactionsUnsupported for connections opened from DSN. Every statement runs on its own
+	// when it isn't set, Firebolt has no savepoints either way
+	Transactions bool
+	// Retry retries idempotent statements failing with transient errors, statements aren't retried when it is nil
+	Retry *RetryPolicy
+	// Engines are additional engines of the database, queries are routed to read engines
+	// and the other statements to the engine of DSN and write engines. Statements are prepared on every engine
+	// with PrepareStmt, transactions begin on the engine of DSN and their statements aren't routed
+	Engines []Engine
+	// EnginePolicy chooses one of the engines of a role, RoundRobinPolicy by default
+	EnginePolicy EnginePolicy
+	// SlowQueryThreshold logs calls taking longer with their statistics as warnings, 0 disables the log
+	SlowQueryThreshold time.Duration
+
+	// positionalParameters is set by Initialize when PositionalParameters are supported
+	positionalParameters bool
 }
 
 type Dialector struct {
@@ -25,15 +58,11 @@ type Dialector struct {
 	Conn gorm.ConnPool
 }
 
-const (
-	driverName = "firebolt"
-)
-
 var (
 	// CreateClauses create clauses
 	CreateClauses = []string{"INSERT", "VALUES"}
 	// QueryClauses query clauses
-	QueryClauses = []string{}
+	QueryClauses = []string{"WITH", "SELECT", "FROM", "WHERE", "GROUP BY", "QUALIFY", "ORDER BY", "LIMIT", "FOR"}
 	// UpdateClauses update clauses
 	UpdateClauses = []string{"UPDATE", "SET", "WHERE", "ORDER BY", "LIMIT"}
 	// DeleteClauses delete clauses
@@ -56,21 +85,74 @@ func (dialector Dialector) Initialize(db *gorm.DB) (err error) {
 
 	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{
 		CreateClauses: CreateClauses,
+		QueryClauses:  QueryClauses,
 	})
 
-	if db.ConnPool, err = sql.Open(driverName, dialector.DSN); err != nil {
+	db.Callback().Create().Replace("gorm:create", dialector.create(db.Callback().Create().Get("gorm:create")))
+	// connections opened from DSN apply async_execution, connection pools set by the user are checked
+	if !dialector.opensConnections() {
+		if err = db.Callback().Row().Before("gorm:row").Register("firebolt:async", checkAsyncExecution); err != nil {
+			return err
+		}
+	}
+	if err = dialector.registerStatsCallbacks(db); err != nil {
 		return err
 	}
 
+	if dialector.Conn != nil {
+		db.ConnPool = dialector.Conn
+	} else if dialector.Transactions {
+		return fmt.Errorf("%w: connections opened from DSN don't carry transactions, set Dialector.Conn", ErrTransactionsUnsupported)
+	} else {
+		db.ConnPool = sql.OpenDB(dialector.connector(dialector.DSN))
+	}
+
+	if len(dialector.Engines) > 0 {
+		if err = dialector.registerEngineRouter(db); err != nil {
+			return err
+		}
+	}
+
+	if dialector.PositionalParameters {
+		dialector.positionalParameters = db.DryRun || supportsPositionalParameters(db.ConnPool)
+		if !dialector.positionalParameters {
+			db.Logger.Warn(context.Background(), "positional parameters are not supported, falling back to ? placeholders")
+		}
+	}
+
 	for k, v := range dialector.clauseBuilders() {
 		db.ClauseBuilders[k] = v
 	}
 	return
 }
 
+// connector opens connections to the engine of the DSN
+func (dialector Dialector) connector(dsn string) sessionConnector {
+	return sessionConnector{
+		Connector:     dsnConnector{dsn: dsn, driver: fireboltgosdk.FireboltDriver{}},
+		cancelQueries: dialector.CancelQueries,
+		retry:         dialector.Retry,
+	}
+}
+
+// opensConnections tells whether the connections of every engine are opened from DSN
+func (dialector Dialector) opensConnections() bool {
+	if dialector.Conn != nil {
+		return false
+	}
+	for _, engine := range dialector.Engines {
+		if engine.Conn != nil {
+			return false
+		}
+	}
+	return true
+}
+
 func (dialector Dialector) Apply(config *gorm.Config) error {
-	// Firebolt doesn't support transactions
-	config.SkipDefaultTransaction = true
+	// Firebolt runs every statement on its own unless transactions are enabled
+	if !dialector.Transactions {
+		config.SkipDefaultTransaction = true
+	}
 	return nil
 }
 
@@ -100,6 +182,10 @@ func (dialector Dialector) DataTypeOf(field *schema.Field) string {
 	case schema.Bytes:
 		return "BYTEA"
 	}
+	// types set with the type tag, e.g. `gorm:"type:ARRAY(TEXT)"`
+	if field.DataType != "" {
+		return string(field.DataType)
+	}
 	return fmt.Sprintf("UNKNOWN DATETYPE: %s", field.DataType)
 }
 
@@ -107,10 +193,30 @@ func (dialector Dialector) DefaultValueOf(field *schema.Field) clause.Expression
 	return clause.Expr{SQL: "DEFAULT"}
 }
 
+// questionMarksKey makes BindVarTo write ? placeholders for a statement using $N for something else, e.g. COPY
+const questionMarksKey = "firebolt:question_marks"
+
 func (dialector Dialector) BindVarTo(writer clause.Writer, stmt *gorm.Statement, v interface{}) {
+	if _, questionMarks := stmt.Settings.Load(questionMarksKey); dialector.Config != nil && dialector.positionalParameters && !questionMarks {
+		_ = writer.WriteByte('$')
+		_, _ = writer.WriteString(strconv.Itoa(len(stmt.Vars)))
+		return
+	}
 	_ = writer.WriteByte('?')
 }
 
+// supportsPositionalParameters checks whether $1 parameters are bound by the server
+func supportsPositionalParameters(pool gorm.ConnPool) bool {
+	rows, err := pool.QueryContext(context.Background(), "SELECT $1", 1)
+	if err != nil {
+		return false
+	}
+	defer rows.Close()
+
+	var value int64
+	return rows.Next() && rows.Scan(&value) == nil && value == 1
+}
+
 func (dialector Dialector) QuoteTo(writer clause.Writer, str string) {
 	// Quoting table and column names
 	_ = writer.WriteByte('"')
@@ -128,14 +234,38 @@ func (dialector Dialector) QuoteTo(writer clause.Writer, str string) {
 	}
 }
 
+var numericPlaceholder = regexp.MustCompile(`\$(\d+)`)
+
 func (dialector Dialector) Explain(sql string, vars ...interface{}) string {
-	return logger.ExplainSQL(sql, nil, `'`, vars...)
+	// statements bound with ? placeholders, see questionMarksKey, are explained as such
+	if dialector.Config != nil && dialector.positionalParameters && len(vars) > 0 && !strings.Contains(sql, "?") {
+		return logger.ExplainSQL(sql, numericPlaceholder, `'`, redactSecrets(vars)...)
+	}
+	return logger.ExplainSQL(sql, nil, `'`, redactSecrets(vars)...)
+}
+
+// redactSecrets returns vars with Secret values replaced, without changing the statement's own vars
+func redactSecrets(vars []interface{}) []interface{} {
+	for idx, v := range vars {
+		if _, ok := v.(Secret); ok {
+			redacted := append([]interface{}{}, vars...)
+			for ; idx < len(redacted); idx++ {
+				if _, ok := redacted[idx].(Secret); ok {
+					redacted[idx] = "***"
+				}
+			}
+			return redacted
+		}
+	}
+	return vars
 }
 
 const (
 	// ClauseValues for clause.ClauseBuilder VALUES key
 	ClauseValues  = "VALUES"
 	ClauseGroupBy = "GROUP BY"
+	ClauseWith    = "WITH"
+	ClauseQualify = "QUALIFY"
 )
 
 func (dialector Dialector) clauseBuilders() map[string]clause.ClauseBuilder {
@@ -151,6 +281,11 @@ func (dialector Dialector) clauseBuilders() map[string]clause.ClauseBuilder {
 		},
 		ClauseGroupBy: func(c clause.Clause, builder clause.Builder) {
 			if groupBy, ok := c.Expression.(clause.GroupBy); ok {
+				if groupings, ok := c.AfterNameExpression.(groupingList); ok && len(groupings) > 0 {
+					// ROLLUP, CUBE and GROUPING SETS are listed after plain columns
+					buildGroupBy(groupBy, groupings, builder)
+					return
+				}
 				if len(groupBy.Columns) == 1 && strings.ToLower(groupBy.Columns[0].Name) == "all" {
 					// If we want to group by all, replace groupBy expression with raw "GROUP BY ALL" sql
 					c.Expression = clause.Expr{SQL: "ALL"}
@@ -158,6 +293,34 @@ func (dialector Dialector) clauseBuilders() map[string]clause.ClauseBuilder {
 			}
 			c.Build(builder)
 		},
+		ClauseWith: func(c clause.Clause, builder clause.Builder) {
+			if with, ok := c.Expression.(WithClause); ok {
+				if len(with.CTEs) == 0 {
+					if st, ok := builder.(*gorm.Statement); ok {
+						_ = st.AddError(errors.New("WITH clause requires at least one common table expression"))
+					}
+					return
+				}
+				for _, cte := range with.CTEs {
+					if cte.Name == "" || cte.Query == nil {
+						if st, ok := builder.(*gorm.Statement); ok {
+							_ = st.AddError(errors.New("Common table expressions require a name and a query"))
+						}
+						return
+					}
+				}
+			}
+			c.Build(builder)
+		},
+		ClauseQualify: func(c clause.Clause, builder clause.Builder) {
+			if qualify, ok := c.Expression.(QualifyClause); ok && qualify.err != nil {
+				if st, ok := builder.(*gorm.Statement); ok {
+					_ = st.AddError(qualify.err)
+				}
+				return
+			}
+			c.Build(builder)
+		},
 	}
 
 	return clauseBuilders
@@ -1,10 +1,18 @@
 package firebolt
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 func runTestQuoteTo(t *testing.T, input, expected string) {
@@ -20,3 +28,230 @@ func TestQuoteTo(t *testing.T) {
 	runTestQuoteTo(t, "nested.nested.name", "\"nested\".\"nested\".\"name\"")
 	runTestQuoteTo(t, "", "\"\"")
 }
+
+// fakeConnPool records executed statements instead of sending them to Firebolt
+type fakeConnPool struct {
+	mu    sync.Mutex
+	execs []string
+	// execErr is returned for statements it returns a non-nil error for
+	execErr func(query string) error
+}
+
+func (p *fakeConnPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errors.New("not supported by fakeConnPool")
+}
+
+func (p *fakeConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	p.mu.Lock()
+	p.execs = append(p.execs, query)
+	p.mu.Unlock()
+	if p.execErr != nil {
+		if err := p.execErr(query); err != nil {
+			return nil, err
+		}
+	}
+	return fakeExecResult(insertedRows(query)), nil
+}
+
+// fakeExecResult reports the rows of an INSERT as affected and no last insert ID, like the SDK
+type fakeExecResult int64
+
+func (r fakeExecResult) LastInsertId() (int64, error) {
+	return 0, nil
+}
+
+func (r fakeExecResult) RowsAffected() (int64, error) {
+	return int64(r), nil
+}
+
+// insertedRows counts the rows of an INSERT ... VALUES statement
+func insertedRows(query string) int {
+	if !strings.HasPrefix(query, "INSERT") {
+		return 0
+	}
+	return strings.Count(query, "(?")
+}
+
+func (p *fakeConnPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("not supported by fakeConnPool")
+}
+
+func (p *fakeConnPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+// openTestDB opens a gorm session on top of the connection pool without connecting to Firebolt
+func openTestDB(t *testing.T, config Config, pool gorm.ConnPool, gormConfig *gorm.Config) *gorm.DB {
+	gormConfig.DisableAutomaticPing = true
+	if gormConfig.Logger == nil {
+		gormConfig.Logger = logger.Discard
+	}
+	db, err := gorm.Open(&Dialector{Config: &config, Conn: pool}, gormConfig)
+	if err != nil {
+		t.Fatalf("failed to open gorm session: %v", err)
+	}
+	return db
+}
+
+// dryRunDB opens a gorm session which only builds statements
+func dryRunDB(t *testing.T) *gorm.DB {
+	return openTestDB(t, Config{}, &fakeConnPool{}, &gorm.Config{DryRun: true})
+}
+
+// fakeResult is a result set returned by fakeDriver
+type fakeResult struct {
+	columns []string
+	rows    [][]driver.Value
+	// stats are reported as the statistics of the statement when set
+	stats *QueryStats
+}
+
+// fakeDriver is a database/sql driver answering queries with the handler instead of sending them to Firebolt
+type fakeDriver struct {
+	mu sync.Mutex
+	// queries are the received statements
+	queries []string
+	// args are the arguments of the received statements
+	args    [][]driver.NamedValue
+	handler func(query string, args []driver.NamedValue) (*fakeResult, error)
+	// connects is the number of opened connections
+	connects int
+	// prepared are the prepared statements
+	prepared []string
+}
+
+func (d *fakeDriver) Connect(ctx context.Context) (driver.Conn, error) {
+	d.mu.Lock()
+	d.connects++
+	d.mu.Unlock()
+	return &fakeConn{driver: d}, nil
+}
+
+func (d *fakeDriver) Driver() driver.Driver {
+	return nil
+}
+
+func (d *fakeDriver) received() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string{}, d.queries...)
+}
+
+func (d *fakeDriver) run(query string, args []driver.NamedValue) (*fakeResult, error) {
+	d.mu.Lock()
+	d.queries = append(d.queries, query)
+	d.args = append(d.args, args)
+	handler := d.handler
+	d.mu.Unlock()
+
+	if handler == nil {
+		return &fakeResult{}, nil
+	}
+	result, err := handler(query, args)
+	if result == nil {
+		result = &fakeResult{}
+	}
+	return result, err
+}
+
+type fakeConn struct {
+	driver *fakeDriver
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.driver.mu.Lock()
+	c.driver.prepared = append(c.driver.prepared, query)
+	c.driver.mu.Unlock()
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("Transactions are not implemented in firebolt")
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	result, err := c.driver.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	if result.stats != nil {
+		ReportQueryStats(ctx, *result.stats)
+	}
+	return &fakeRows{result: result}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	result, err := c.driver.run(query, args)
+	if err == nil && result.stats != nil {
+		ReportQueryStats(ctx, *result.stats)
+	}
+	return fakeExecResult(insertedRows(query)), err
+}
+
+// fakeStmt is a prepared statement of fakeConn, it runs the statement like the connection does
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported by fakeStmt")
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported by fakeStmt")
+}
+
+func (s *fakeStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *fakeStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+type fakeRows struct {
+	result *fakeResult
+	cursor int
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.result.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.cursor == len(r.result.rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.rows[r.cursor])
+	r.cursor++
+	return nil
+}
+
+// openFakeDB opens a gorm session on top of fakeDriver
+func openFakeDB(t *testing.T, config Config, handler func(query string, args []driver.NamedValue) (*fakeResult, error)) (*gorm.DB, *fakeDriver) {
+	fake := &fakeDriver{handler: handler}
+	return openTestDB(t, config, sql.OpenDB(fake), &gorm.Config{}), fake
+}
@@ -0,0 +1,108 @@
+package firebolt
+
+import "gorm.io/gorm/clause"
+
+// GroupingExpr is ROLLUP, CUBE or GROUPING SETS element of GROUP BY clause,
+// it is added to the query with db.Clauses and can be combined with db.Group columns, e.g.
+//
+//	db.Model(&Sale{}).Select("region, product, sum(amount)").Group("year").Clauses(firebolt.Rollup("region", "product"))
+type GroupingExpr struct {
+	Kind string
+	Sets [][]clause.Column
+}
+
+// Rollup groups by the columns with subtotals for every prefix of the column list
+func Rollup(columns ...string) GroupingExpr {
+	return GroupingExpr{Kind: "ROLLUP", Sets: [][]clause.Column{columnsOf(columns)}}
+}
+
+// Cube groups by the columns with subtotals for every combination of the columns
+func Cube(columns ...string) GroupingExpr {
+	return GroupingExpr{Kind: "CUBE", Sets: [][]clause.Column{columnsOf(columns)}}
+}
+
+// GroupingSets groups by each of the column sets, an empty set stands for the grand total
+func GroupingSets(sets ...[]string) GroupingExpr {
+	grouping := GroupingExpr{Kind: "GROUPING SETS", Sets: make([][]clause.Column, 0, len(sets))}
+	for _, set := range sets {
+		grouping.Sets = append(grouping.Sets, columnsOf(set))
+	}
+	return grouping
+}
+
+func (grouping GroupingExpr) Name() string {
+	return ClauseGroupBy
+}
+
+func (grouping GroupingExpr) Build(builder clause.Builder) {
+	builder.WriteString(grouping.Kind)
+	builder.WriteByte('(')
+	if grouping.Kind == "GROUPING SETS" {
+		for idx, set := range grouping.Sets {
+			if idx > 0 {
+				builder.WriteByte(',')
+			}
+			builder.WriteByte('(')
+			writeColumns(builder, set)
+			builder.WriteByte(')')
+		}
+	} else if len(grouping.Sets) > 0 {
+		writeColumns(builder, grouping.Sets[0])
+	}
+	builder.WriteByte(')')
+}
+
+// MergeClause keeps groupings next to the GROUP BY expression, so they survive db.Group and db.Having merges
+func (grouping GroupingExpr) MergeClause(c *clause.Clause) {
+	groupings, _ := c.AfterNameExpression.(groupingList)
+	c.AfterNameExpression = append(append(groupingList{}, groupings...), grouping)
+	if c.Expression == nil {
+		c.Expression = clause.GroupBy{}
+	}
+	c.Name = ClauseGroupBy
+}
+
+// groupingList holds GroupingExpr elements of GROUP BY clause
+type groupingList []GroupingExpr
+
+func (groupings groupingList) Build(builder clause.Builder) {
+	for idx, grouping := range groupings {
+		if idx > 0 {
+			builder.WriteByte(',')
+		}
+		grouping.Build(builder)
+	}
+}
+
+// GroupingFunc is GROUPING() function, which tells whether a column is aggregated in a subtotal row
+type GroupingFunc struct {
+	Columns []clause.Column
+}
+
+// Grouping returns GROUPING() function of the columns to be used as a Select argument, e.g.
+//
+//	db.Select("region, sum(amount), ?", firebolt.Grouping("region"))
+func Grouping(columns ...string) GroupingFunc {
+	return GroupingFunc{Columns: columnsOf(columns)}
+}
+
+func (grouping GroupingFunc) Build(builder clause.Builder) {
+	builder.WriteString("GROUPING(")
+	writeColumns(builder, grouping.Columns)
+	builder.WriteByte(')')
+}
+
+// buildGroupBy builds GROUP BY clause with groupings, db.Group columns go first
+func buildGroupBy(groupBy clause.GroupBy, groupings groupingList, builder clause.Builder) {
+	builder.WriteString("GROUP BY ")
+	writeColumns(builder, groupBy.Columns)
+	if len(groupBy.Columns) > 0 {
+		builder.WriteByte(',')
+	}
+	groupings.Build(builder)
+
+	if len(groupBy.Having) > 0 {
+		builder.WriteString(" HAVING ")
+		clause.Where{Exprs: groupBy.Having}.Build(builder)
+	}
+}
@@ -0,0 +1,55 @@
+package firebolt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testSale struct {
+	ID      int
+	Year    int
+	Region  string
+	Product string
+	Amount  float64
+}
+
+func TestGroupByRollup(t *testing.T) {
+	db := dryRunDB(t)
+
+	stmt := db.Model(&testSale{}).Select("region, product, sum(amount)").
+		Clauses(Rollup("region", "product")).Find(&[]testSale{}).Statement
+	assert.Equal(t, `SELECT region, product, sum(amount) FROM "test_sales" GROUP BY ROLLUP("region","product")`, stmt.SQL.String())
+
+	stmt = db.Model(&testSale{}).Select("year, region, product, sum(amount)").
+		Group("year").Clauses(Cube("region", "product")).Having("sum(amount) > ?", 10).Find(&[]testSale{}).Statement
+	assert.Equal(t, `SELECT year, region, product, sum(amount) FROM "test_sales" GROUP BY "year",CUBE("region","product") HAVING sum(amount) > ?`, stmt.SQL.String())
+	assert.Equal(t, []interface{}{10}, stmt.Vars)
+}
+
+func TestGroupByGroupingSets(t *testing.T) {
+	db := dryRunDB(t)
+
+	stmt := db.Model(&testSale{}).Select("region, product, sum(amount), ?", Grouping("region", "product")).
+		Clauses(GroupingSets([]string{"region", "product"}, []string{"region"}, []string{})).Find(&[]testSale{}).Statement
+	assert.Equal(t,
+		`SELECT region, product, sum(amount), GROUPING("region","product") FROM "test_sales" GROUP BY GROUPING SETS(("region","product"),("region"),())`,
+		stmt.SQL.String())
+}
+
+func TestGroupByGroupingsMerge(t *testing.T) {
+	db := dryRunDB(t)
+
+	stmt := db.Model(&testSale{}).Select("region, extract(year from created_at), sum(amount)").
+		Clauses(Rollup("region")).Group("product").Clauses(Rollup("extract(year from created_at)")).Find(&[]testSale{}).Statement
+	assert.Equal(t,
+		`SELECT region, extract(year from created_at), sum(amount) FROM "test_sales" GROUP BY "product",ROLLUP("region"),ROLLUP(extract(year from created_at))`,
+		stmt.SQL.String())
+}
+
+func TestGroupByAll(t *testing.T) {
+	db := dryRunDB(t)
+
+	stmt := db.Model(&testSale{}).Select("region, sum(amount)").Group("all").Find(&[]testSale{}).Statement
+	assert.Equal(t, `SELECT region, sum(amount) FROM "test_sales" GROUP BY ALL`, stmt.SQL.String())
+}
@@ -0,0 +1,88 @@
+package firebolt
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// InsertFrom inserts the result of the source query into the target model's table with INSERT INTO ... SELECT.
+// Columns selected on target (e.g. db.Model(&Daily{}).Select("day", "total")) make up the column list,
+// otherwise all the target's columns are used in the schema order; the source has to select values in the same order.
+// RowsAffected of the result is the one reported by the driver
+func InsertFrom(target *gorm.DB, source *gorm.DB) *gorm.DB {
+	tx := target.Session(&gorm.Session{})
+	model := target.Statement.Model
+	if model == nil {
+		model = target.Statement.Dest
+	}
+	if model == nil {
+		_ = tx.AddError(errors.New("InsertFrom requires a target model"))
+		return tx
+	}
+
+	stmt := &gorm.Statement{DB: target}
+	if err := stmt.Parse(model); err != nil {
+		_ = tx.AddError(err)
+		return tx
+	}
+	table := stmt.Table
+	if target.Statement.Table != "" {
+		table = target.Statement.Table
+	}
+
+	columns, err := insertColumns(stmt.Schema, target.Statement.Selects, target.Statement.Omits)
+	if err != nil {
+		_ = tx.AddError(err)
+		return tx
+	}
+
+	var sql strings.Builder
+	sql.WriteString("INSERT INTO ")
+	target.Dialector.QuoteTo(&sql, table)
+	sql.WriteString(" (")
+	for idx, column := range columns {
+		if idx > 0 {
+			sql.WriteByte(',')
+		}
+		target.Dialector.QuoteTo(&sql, column)
+	}
+	sql.WriteString(") ?")
+
+	return target.Session(&gorm.Session{NewDB: true}).Exec(sql.String(), source)
+}
+
+// insertColumns returns database names of the selected fields, or of all the creatable fields when nothing is selected
+func insertColumns(s *schema.Schema, selects, omits []string) ([]string, error) {
+	omitted := make(map[string]bool, len(omits))
+	for _, name := range omits {
+		if field := s.LookUpField(name); field != nil {
+			omitted[field.DBName] = true
+		} else {
+			omitted[name] = true
+		}
+	}
+
+	columns := make([]string, 0, len(s.DBNames))
+	if len(selects) > 0 {
+		for _, name := range selects {
+			field := s.LookUpField(name)
+			if field == nil || field.DBName == "" {
+				return nil, errors.New("unknown target column " + name)
+			}
+			if !omitted[field.DBName] {
+				columns = append(columns, field.DBName)
+			}
+		}
+		return columns, nil
+	}
+
+	for _, dbName := range s.DBNames {
+		if field := s.FieldsByDBName[dbName]; field.Creatable && !omitted[dbName] {
+			columns = append(columns, dbName)
+		}
+	}
+	return columns, nil
+}
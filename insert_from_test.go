@@ -0,0 +1,66 @@
+package firebolt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type testEvent struct {
+	ID        int
+	Amount    float64
+	CreatedAt time.Time
+}
+
+type testDailyTotal struct {
+	Day   time.Time
+	Total float64
+	Count int
+}
+
+func TestInsertFrom(t *testing.T) {
+	db := dryRunDB(t)
+
+	source := db.Model(&testEvent{}).
+		Select("date_trunc('day', created_at), sum(amount), count(*)").
+		Where("amount > ?", 10).
+		Group("date_trunc('day', created_at)")
+	stmt := InsertFrom(db.Model(&testDailyTotal{}), source).Statement
+	assert.Equal(t,
+		`INSERT INTO "test_daily_totals" ("day","total","count") SELECT date_trunc('day', created_at), sum(amount), count(*) FROM "test_events" WHERE amount > ? GROUP BY date_trunc('day', created_at)`,
+		stmt.SQL.String())
+	assert.Equal(t, []interface{}{10}, stmt.Vars)
+}
+
+func TestInsertFromSelectedColumns(t *testing.T) {
+	db := dryRunDB(t)
+
+	source := db.Table("test_events").Select("created_at, amount")
+	stmt := InsertFrom(db.Model(&testDailyTotal{}).Select("Day", "total"), source).Statement
+	assert.Equal(t, `INSERT INTO "test_daily_totals" ("day","total") SELECT created_at, amount FROM "test_events"`, stmt.SQL.String())
+
+	stmt = InsertFrom(db.Model(&testDailyTotal{}).Omit("count"), source).Statement
+	assert.Equal(t, `INSERT INTO "test_daily_totals" ("day","total") SELECT created_at, amount FROM "test_events"`, stmt.SQL.String())
+
+	assert.Error(t, InsertFrom(db.Model(&testDailyTotal{}).Select("unknown"), source).Error)
+}
+
+func TestInsertFromExec(t *testing.T) {
+	pool := &fakeConnPool{}
+	db := openTestDB(t, Config{}, pool, &gorm.Config{})
+
+	assert.NoError(t, InsertFrom(db.Model(&testDailyTotal{}), db.Table("test_events").Select("created_at, amount, 1")).Error)
+	assert.Equal(t, []string{`INSERT INTO "test_daily_totals" ("day","total","count") SELECT created_at, amount, 1 FROM "test_events"`}, pool.execs)
+}
+
+func TestInsertFromErrorKeepsDB(t *testing.T) {
+	db, fake := openFakeDB(t, Config{}, nil)
+
+	assert.EqualError(t, InsertFrom(db, db.Table("test_events")).Error, "InsertFrom requires a target model")
+	// the error is kept in the result, the db InsertFrom got still runs statements
+	assert.NoError(t, db.Error)
+	assert.NoError(t, db.Find(&[]testEvent{}).Error)
+	assert.Equal(t, []string{`SELECT * FROM "test_events"`}, fake.received())
+}
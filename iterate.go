@@ -0,0 +1,188 @@
+package firebolt
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Cursor is a position of Iterate, the primary index values of the last processed row.
+// It can be stored as JSON and passed to After to resume the iteration in a later run
+type Cursor struct {
+	Key []interface{} `json:"key"`
+}
+
+const cursorClauseName = "firebolt:cursor"
+
+// CursorClause starts Iterate after the row of the cursor
+type CursorClause struct {
+	Cursor Cursor
+}
+
+// After returns a clause resuming Iterate after the row of the cursor, an empty cursor starts from the first row
+func After(cursor Cursor) CursorClause {
+	return CursorClause{Cursor: cursor}
+}
+
+func (after CursorClause) ModifyStatement(stmt *gorm.Statement) {
+	stmt.Clauses[cursorClauseName] = clause.Clause{Name: cursorClauseName, Expression: after}
+}
+
+func (after CursorClause) Build(clause.Builder) {
+}
+
+// Iterate calls fn for every row of the model's table matching the conditions of db in the primary index order, e.g.
+//
+//	firebolt.Iterate(db.Model(&Order{}).Where("day >= ?", day).Clauses(firebolt.After(saved)), 10000,
+//		func(row interface{}, cursor firebolt.Cursor) error {
+//			order := row.(*Order)
+//			...
+//		})
+//
+// Rows are read in batches of batchSize, every batch continues after the primary index values of the last row,
+// so reading a batch doesn't get slower the further the iteration goes, unlike with OFFSET. A batch is read
+// and its rows are closed before fn is called, so fn can run statements even when the pool has a single connection.
+// fn gets a new model value for every row and the cursor of the row, the iteration stops with the error returned by fn
+func Iterate(db *gorm.DB, batchSize int, fn func(row interface{}, cursor Cursor) error) *gorm.DB {
+	tx := db.Session(&gorm.Session{})
+	if batchSize <= 0 {
+		_ = tx.AddError(fmt.Errorf("Iterate requires a positive batch size, got %d", batchSize))
+		return tx
+	}
+	model := db.Statement.Model
+	if model == nil {
+		_ = tx.AddError(errors.New("Iterate requires a model"))
+		return tx
+	}
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(model); err != nil {
+		_ = tx.AddError(err)
+		return tx
+	}
+	primaryFields := stmt.Schema.PrimaryFields
+	if len(primaryFields) == 0 {
+		_ = tx.AddError(errors.New("Iterate requires a model with a primary index, " + stmt.Schema.Name + " has none"))
+		return tx
+	}
+
+	var key []interface{}
+	if c, ok := db.Statement.Clauses[cursorClauseName]; ok {
+		var err error
+		if key, err = cursorKey(primaryFields, c.Expression.(CursorClause).Cursor); err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+	}
+
+	order := make([]clause.OrderByColumn, 0, len(primaryFields))
+	for _, field := range primaryFields {
+		order = append(order, clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}})
+	}
+	modelType := stmt.Schema.ModelType
+
+	for {
+		query := tx.Clauses(clause.OrderBy{Columns: order}).Limit(batchSize)
+		if key != nil {
+			query = query.Where(keysetCondition(primaryFields, key))
+		}
+		rows, err := query.Rows()
+		if err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+		batch, err := scanBatch(tx, rows, modelType)
+		if err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+
+		for _, row := range batch {
+			key = make([]interface{}, len(primaryFields))
+			for idx, field := range primaryFields {
+				key[idx], _ = field.ValueOf(stmt.Context, row.Elem())
+			}
+			tx.RowsAffected++
+			if err = fn(row.Interface(), Cursor{Key: key}); err != nil {
+				_ = tx.AddError(err)
+				return tx
+			}
+		}
+		if len(batch) < batchSize {
+			return tx
+		}
+	}
+}
+
+// scanBatch reads all the rows of a batch into new model values and closes them
+func scanBatch(tx *gorm.DB, rows *sql.Rows, modelType reflect.Type) (batch []reflect.Value, err error) {
+	defer func() {
+		if closeErr := rows.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	for rows.Next() {
+		row := reflect.New(modelType)
+		if err = tx.ScanRows(rows, row.Interface()); err != nil {
+			return nil, err
+		}
+		batch = append(batch, row)
+	}
+	return batch, rows.Err()
+}
+
+// keysetCondition selects the rows after the key in the primary index order, e.g. for the index a, b:
+// a >= ? AND (a > ? OR a = ? AND b > ?), the first comparison lets the engine prune by the leading column
+func keysetCondition(fields []*schema.Field, key []interface{}) clause.Expression {
+	column := func(field *schema.Field) clause.Column {
+		return clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+	}
+
+	after := make([]clause.Expression, 0, len(fields))
+	for idx, field := range fields {
+		conds := make([]clause.Expression, 0, idx+1)
+		for prev := 0; prev < idx; prev++ {
+			conds = append(conds, clause.Eq{Column: column(fields[prev]), Value: key[prev]})
+		}
+		conds = append(conds, clause.Gt{Column: column(field), Value: key[idx]})
+		after = append(after, clause.And(conds...))
+	}
+
+	if len(fields) == 1 {
+		return after[0]
+	}
+	return clause.And(clause.Gte{Column: column(fields[0]), Value: key[0]}, clause.Or(after...))
+}
+
+// cursorKey converts the values of the cursor decoded from JSON to the types of the primary fields
+func cursorKey(fields []*schema.Field, cursor Cursor) ([]interface{}, error) {
+	if len(cursor.Key) == 0 {
+		return nil, nil
+	}
+	if len(cursor.Key) != len(fields) {
+		return nil, fmt.Errorf("cursor has %d values, the primary index has %d columns", len(cursor.Key), len(fields))
+	}
+	key := make([]interface{}, len(fields))
+	for idx, field := range fields {
+		value := cursor.Key[idx]
+		if value == nil || reflect.TypeOf(value) == field.FieldType {
+			key[idx] = value
+			continue
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		converted := reflect.New(field.FieldType)
+		if err = json.Unmarshal(data, converted.Interface()); err != nil {
+			return nil, fmt.Errorf("cursor value %s doesn't fit %s: %w", data, field.Name, err)
+		}
+		key[idx] = converted.Elem().Interface()
+	}
+	return key, nil
+}
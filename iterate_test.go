@@ -0,0 +1,114 @@
+package firebolt
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIterate(t *testing.T) {
+	day := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+	batches := [][][]driver.Value{
+		{{day, "de", 1.5}, {day, "fr", 2.5}},
+		{{day.AddDate(0, 0, 1), "de", 3.5}},
+	}
+	db, fake := openFakeDB(t, Config{}, func(query string, args []driver.NamedValue) (*fakeResult, error) {
+		if len(batches) == 0 {
+			return &fakeResult{columns: []string{"day", "country", "revenue"}}, nil
+		}
+		rows := batches[0]
+		batches = batches[1:]
+		return &fakeResult{columns: []string{"day", "country", "revenue"}, rows: rows}, nil
+	})
+
+	var (
+		revenues []testDailyRevenue
+		last     Cursor
+	)
+	result := Iterate(db.Model(&testDailyRevenue{}).Where("revenue > ?", 0), 2, func(row interface{}, cursor Cursor) error {
+		revenues = append(revenues, *row.(*testDailyRevenue))
+		last = cursor
+		return nil
+	})
+	assert.NoError(t, result.Error)
+	assert.Equal(t, int64(3), result.RowsAffected)
+	assert.Equal(t, []testDailyRevenue{
+		{Day: day, Country: "de", Revenue: 1.5},
+		{Day: day, Country: "fr", Revenue: 2.5},
+		{Day: day.AddDate(0, 0, 1), Country: "de", Revenue: 3.5},
+	}, revenues)
+	assert.Equal(t, Cursor{Key: []interface{}{day.AddDate(0, 0, 1), "de"}}, last)
+
+	assert.Equal(t, []string{
+		`SELECT * FROM "test_daily_revenues" WHERE revenue > ? ORDER BY "test_daily_revenues"."day","test_daily_revenues"."country" LIMIT 2`,
+		`SELECT * FROM "test_daily_revenues" WHERE revenue > ? AND ("test_daily_revenues"."day" >= ? AND ("test_daily_revenues"."day" > ? OR ("test_daily_revenues"."day" = ? AND "test_daily_revenues"."country" > ?))) ORDER BY "test_daily_revenues"."day","test_daily_revenues"."country" LIMIT 2`,
+	}, fake.received())
+	assert.Equal(t, []driver.NamedValue{
+		{Ordinal: 1, Value: int64(0)}, {Ordinal: 2, Value: day}, {Ordinal: 3, Value: day}, {Ordinal: 4, Value: day}, {Ordinal: 5, Value: "fr"},
+	}, fake.args[1])
+
+	// the cursor is stored between runs and the iteration resumes after its row
+	data, err := json.Marshal(last)
+	assert.NoError(t, err)
+	var saved Cursor
+	assert.NoError(t, json.Unmarshal(data, &saved))
+
+	stop := errors.New("stop")
+	err = Iterate(db.Model(&testDailyRevenue{}).Clauses(After(saved)), 10, func(row interface{}, cursor Cursor) error {
+		return stop
+	}).Error
+	assert.NoError(t, err)
+	assert.Equal(t, day.AddDate(0, 0, 1), fake.args[2][0].Value.(time.Time).UTC())
+	assert.Equal(t, "de", fake.args[2][3].Value)
+
+	batches = [][][]driver.Value{{{day, "de", 1.5}}}
+	err = Iterate(db.Model(&testDailyRevenue{}), 10, func(row interface{}, cursor Cursor) error {
+		return stop
+	}).Error
+	assert.ErrorIs(t, err, stop)
+
+	err = Iterate(db.Model(&testDailyRevenue{}).Clauses(After(Cursor{Key: []interface{}{1}})), 10, nil).Error
+	assert.EqualError(t, err, "cursor has 1 values, the primary index has 2 columns")
+}
+
+func TestIterateErrorKeepsDB(t *testing.T) {
+	db, fake := openFakeDB(t, Config{}, nil)
+
+	assert.EqualError(t, Iterate(db, 10, nil).Error, "Iterate requires a model")
+	assert.NoError(t, db.Error)
+	assert.NoError(t, db.Find(&[]testDailyRevenue{}).Error)
+	assert.Equal(t, []string{`SELECT * FROM "test_daily_revenues"`}, fake.received())
+}
+
+func TestIterateSingleConnection(t *testing.T) {
+	day := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+	db, fake := openFakeDB(t, Config{}, func(query string, args []driver.NamedValue) (*fakeResult, error) {
+		if strings.HasPrefix(query, `SELECT * FROM "test_daily_revenues"`) && len(args) == 0 {
+			return &fakeResult{columns: []string{"day", "country", "revenue"}, rows: [][]driver.Value{{day, "de", 1.5}}}, nil
+		}
+		return nil, nil
+	})
+	pool, err := db.DB()
+	assert.NoError(t, err)
+	pool.SetMaxOpenConns(1)
+
+	// fn runs its statements on the only connection, the rows of the batch are closed by then
+	done := make(chan error, 1)
+	go func() {
+		done <- Iterate(db.Model(&testDailyRevenue{}), 10, func(row interface{}, cursor Cursor) error {
+			return db.Exec("INSERT INTO processed VALUES (?)", row.(*testDailyRevenue).Country).Error
+		}).Error
+	}()
+	select {
+	case err = <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Iterate is blocked on the connection")
+	}
+	assert.Equal(t, "INSERT INTO processed VALUES (?)", fake.received()[1])
+}
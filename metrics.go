@@ -0,0 +1,192 @@
+package firebolt
+
+import (
+	"expvar"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Names of the metrics recorded by MetricsPlugin
+const (
+	// MetricQueries counts database calls by operation, table and error class
+	MetricQueries = "firebolt_queries_total"
+	// MetricQueryDuration is the histogram of call durations in seconds by operation and table
+	MetricQueryDuration = "firebolt_query_duration_seconds"
+	// MetricRows counts rows returned or affected by operation and table
+	MetricRows = "firebolt_rows_total"
+	// MetricRowsRead and MetricBytesRead count rows and bytes scanned by the server by operation and table,
+	// they are recorded when the server reports query statistics
+	MetricRowsRead  = "firebolt_rows_read_total"
+	MetricBytesRead = "firebolt_bytes_read_total"
+
+	MetricPoolOpenConnections  = "firebolt_pool_open_connections"
+	MetricPoolInUseConnections = "firebolt_pool_in_use_connections"
+	MetricPoolIdleConnections  = "firebolt_pool_idle_connections"
+	MetricPoolWaitCount        = "firebolt_pool_wait_count"
+	MetricPoolWaitDuration     = "firebolt_pool_wait_duration_seconds"
+)
+
+const metricsStart = "firebolt:metrics_start"
+
+// Labels are metric labels, e.g. operation and table
+type Labels map[string]string
+
+// MetricsRecorder stores the metrics, it can be backed by Prometheus, expvar or any other metrics library
+type MetricsRecorder interface {
+	// AddCounter increases the counter by value
+	AddCounter(name string, labels Labels, value float64)
+	// ObserveHistogram adds an observation to the histogram
+	ObserveHistogram(name string, labels Labels, value float64)
+	// SetGauge sets the gauge to value
+	SetGauge(name string, labels Labels, value float64)
+}
+
+// MetricsPlugin is a gorm.Plugin recording metrics of every create, query, update, delete, row and raw call,
+// and the connection pool statistics of the underlying *sql.DB, e.g.
+//
+//	err := db.Use(&firebolt.MetricsPlugin{Recorder: firebolt.NewExpvarRecorder("firebolt")})
+type MetricsPlugin struct {
+	Recorder MetricsRecorder
+	// Labels are added to every metric, e.g. the engine name
+	Labels Labels
+}
+
+func (plugin *MetricsPlugin) Name() string {
+	return "firebolt:metrics"
+}
+
+func (plugin *MetricsPlugin) Initialize(db *gorm.DB) error {
+	if plugin.Recorder == nil {
+		return fmt.Errorf("%s requires a recorder", plugin.Name())
+	}
+	return registerAround(db, "firebolt:metrics", func(string) func(*gorm.DB) {
+		return func(db *gorm.DB) {
+			db.InstanceSet(metricsStart, time.Now())
+		}
+	}, plugin.after)
+}
+
+func (plugin *MetricsPlugin) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(metricsStart)
+		if !ok {
+			return
+		}
+		elapsed := time.Since(value.(time.Time))
+
+		labels := plugin.labels(Labels{"operation": operation, "table": db.Statement.Table})
+		plugin.Recorder.ObserveHistogram(MetricQueryDuration, labels, elapsed.Seconds())
+		if db.RowsAffected > 0 {
+			plugin.Recorder.AddCounter(MetricRows, labels, float64(db.RowsAffected))
+		}
+		if stats, ok := StatsFrom(db); ok && stats.Reported() {
+			plugin.Recorder.AddCounter(MetricRowsRead, labels, float64(stats.RowsRead))
+			plugin.Recorder.AddCounter(MetricBytesRead, labels, float64(stats.BytesRead))
+		}
+
+		labels = plugin.labels(Labels{"operation": operation, "table": db.Statement.Table, "error_class": string(ClassifyError(db.Error))})
+		plugin.Recorder.AddCounter(MetricQueries, labels, 1)
+
+		if sqlDB, err := db.DB(); err == nil {
+			stats := sqlDB.Stats()
+			labels = plugin.labels(Labels{})
+			plugin.Recorder.SetGauge(MetricPoolOpenConnections, labels, float64(stats.OpenConnections))
+			plugin.Recorder.SetGauge(MetricPoolInUseConnections, labels, float64(stats.InUse))
+			plugin.Recorder.SetGauge(MetricPoolIdleConnections, labels, float64(stats.Idle))
+			plugin.Recorder.SetGauge(MetricPoolWaitCount, labels, float64(stats.WaitCount))
+			plugin.Recorder.SetGauge(MetricPoolWaitDuration, labels, stats.WaitDuration.Seconds())
+		}
+	}
+}
+
+func (plugin *MetricsPlugin) labels(labels Labels) Labels {
+	for name, value := range plugin.Labels {
+		labels[name] = value
+	}
+	return labels
+}
+
+// DefaultDurationBuckets are the upper bounds of ExpvarRecorder histogram buckets in seconds
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// ExpvarRecorder is a MetricsRecorder publishing the metrics with expvar, metrics are keyed by their name and labels,
+// e.g. firebolt_queries_total{error_class="",operation="query",table="orders"}.
+// Histograms are published as _count, _sum and cumulative _bucket values
+type ExpvarRecorder struct {
+	// Buckets are the upper bounds of histogram buckets, DefaultDurationBuckets by default
+	Buckets []float64
+	Vars    *expvar.Map
+
+	mu sync.Mutex
+}
+
+// NewExpvarRecorder returns a recorder publishing the metrics as the expvar map of the name
+func NewExpvarRecorder(name string) *ExpvarRecorder {
+	return &ExpvarRecorder{Buckets: DefaultDurationBuckets, Vars: expvar.NewMap(name)}
+}
+
+func (recorder *ExpvarRecorder) AddCounter(name string, labels Labels, value float64) {
+	recorder.Vars.AddFloat(metricKey(name, labels), value)
+}
+
+func (recorder *ExpvarRecorder) ObserveHistogram(name string, labels Labels, value float64) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	recorder.Vars.AddFloat(metricKey(name+"_count", labels), 1)
+	recorder.Vars.AddFloat(metricKey(name+"_sum", labels), value)
+	buckets := recorder.Buckets
+	if buckets == nil {
+		buckets = DefaultDurationBuckets
+	}
+	for _, bound := range buckets {
+		if value <= bound {
+			recorder.Vars.AddFloat(metricKey(name+"_bucket", withLabel(labels, "le", strconv.FormatFloat(bound, 'g', -1, 64))), 1)
+		}
+	}
+	recorder.Vars.AddFloat(metricKey(name+"_bucket", withLabel(labels, "le", "+Inf")), 1)
+}
+
+func (recorder *ExpvarRecorder) SetGauge(name string, labels Labels, value float64) {
+	gauge := new(expvar.Float)
+	gauge.Set(value)
+	recorder.Vars.Set(metricKey(name, labels), gauge)
+}
+
+// metricKey formats the metric name with its labels sorted by name
+func metricKey(name string, labels Labels) string {
+	if len(labels) == 0 {
+		return name
+	}
+	names := make([]string, 0, len(labels))
+	for label := range labels {
+		names = append(names, label)
+	}
+	sort.Strings(names)
+
+	var key strings.Builder
+	key.WriteString(name + "{")
+	for idx, label := range names {
+		if idx > 0 {
+			key.WriteByte(',')
+		}
+		key.WriteString(label + "=" + strconv.Quote(labels[label]))
+	}
+	key.WriteByte('}')
+	return key.String()
+}
+
+func withLabel(labels Labels, name, value string) Labels {
+	result := make(Labels, len(labels)+1)
+	for label, v := range labels {
+		result[label] = v
+	}
+	result[name] = value
+	return result
+}
@@ -0,0 +1,97 @@
+package firebolt
+
+import (
+	"database/sql/driver"
+	"errors"
+	"expvar"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testRecorder struct {
+	mu         sync.Mutex
+	counters   map[string]float64
+	histograms map[string][]float64
+	gauges     map[string]float64
+}
+
+func newTestRecorder() *testRecorder {
+	return &testRecorder{counters: map[string]float64{}, histograms: map[string][]float64{}, gauges: map[string]float64{}}
+}
+
+func (r *testRecorder) AddCounter(name string, labels Labels, value float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counters[metricKey(name, labels)] += value
+}
+
+func (r *testRecorder) ObserveHistogram(name string, labels Labels, value float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.histograms[metricKey(name, labels)] = append(r.histograms[metricKey(name, labels)], value)
+}
+
+func (r *testRecorder) SetGauge(name string, labels Labels, value float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gauges[metricKey(name, labels)] = value
+}
+
+func TestMetricsPlugin(t *testing.T) {
+	db, _ := openFakeDB(t, Config{}, func(query string, args []driver.NamedValue) (*fakeResult, error) {
+		if strings.HasPrefix(query, "DELETE") {
+			return nil, errors.New(`request returned non ok status code: 400, {"message": "Code: 62. DB::Exception: Syntax error"}`)
+		}
+		if strings.HasPrefix(query, "SELECT") {
+			return &fakeResult{columns: []string{"id", "customer", "amount"}, rows: [][]driver.Value{{int64(1), "acme", 10.0}, {int64(2), "acme", 20.0}}}, nil
+		}
+		return nil, nil
+	})
+	recorder := newTestRecorder()
+	assert.NoError(t, db.Use(&MetricsPlugin{Recorder: recorder, Labels: Labels{"engine": "test_engine"}}))
+
+	assert.NoError(t, db.Where("customer = ?", "acme").Find(&[]testOrder{}).Error)
+	assert.NoError(t, db.Find(&[]testOrder{}).Error)
+	assert.Error(t, db.Where("id = ?", 1).Delete(&testOrder{}).Error)
+	assert.NoError(t, db.Create(&[]testOrder{{ID: 1}, {ID: 2}, {ID: 3}}).Error)
+
+	assert.Equal(t, map[string]float64{
+		`firebolt_queries_total{engine="test_engine",error_class="",operation="query",table="test_orders"}`:        2,
+		`firebolt_queries_total{engine="test_engine",error_class="syntax",operation="delete",table="test_orders"}`: 1,
+		`firebolt_queries_total{engine="test_engine",error_class="",operation="create",table="test_orders"}`:       1,
+		`firebolt_rows_total{engine="test_engine",operation="query",table="test_orders"}`:                          4,
+		`firebolt_rows_total{engine="test_engine",operation="create",table="test_orders"}`:                         3,
+	}, recorder.counters)
+	assert.Len(t, recorder.histograms[`firebolt_query_duration_seconds{engine="test_engine",operation="query",table="test_orders"}`], 2)
+	assert.Contains(t, recorder.gauges, `firebolt_pool_open_connections{engine="test_engine"}`)
+	assert.Contains(t, recorder.gauges, `firebolt_pool_wait_duration_seconds{engine="test_engine"}`)
+
+	assert.Error(t, db.Use(&MetricsPlugin{}))
+}
+
+func TestExpvarRecorder(t *testing.T) {
+	recorder := NewExpvarRecorder("firebolt_test_metrics")
+	recorder.Buckets = []float64{0.1, 1}
+
+	labels := Labels{"operation": "query"}
+	recorder.AddCounter(MetricQueries, labels, 1)
+	recorder.AddCounter(MetricQueries, labels, 1)
+	recorder.ObserveHistogram(MetricQueryDuration, labels, 0.5)
+	recorder.SetGauge(MetricPoolIdleConnections, nil, 3)
+
+	values := map[string]string{}
+	recorder.Vars.Do(func(kv expvar.KeyValue) {
+		values[kv.Key] = kv.Value.String()
+	})
+	assert.Equal(t, map[string]string{
+		`firebolt_queries_total{operation="query"}`:                           "2",
+		`firebolt_query_duration_seconds_count{operation="query"}`:            "1",
+		`firebolt_query_duration_seconds_sum{operation="query"}`:              "0.5",
+		`firebolt_query_duration_seconds_bucket{le="1",operation="query"}`:    "1",
+		`firebolt_query_duration_seconds_bucket{le="+Inf",operation="query"}`: "1",
+		`firebolt_pool_idle_connections`:                                      "3",
+	}, values)
+}
@@ -1,8 +1,11 @@
 package firebolt
 
 import (
+	"database/sql"
 	"fmt"
+	"reflect"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -14,10 +17,35 @@ type Migrator struct {
 	migrator.Migrator
 }
 
+type TableType string
+
+const (
+	FactTable      TableType = "FACT"
+	DimensionTable TableType = "DIMENSION"
+)
+
+// TableTyper is implemented by models, which define type of their tables, fact table is created otherwise
+type TableTyper interface {
+	TableType() TableType
+}
+
+func tableTypeOf(model interface{}) TableType {
+	if typer, ok := model.(TableTyper); ok {
+		return typer.TableType()
+	}
+	return FactTable
+}
+
 // FullDataTypeOf returns field's db full data type
 func (m Migrator) FullDataTypeOf(field *schema.Field) (expr clause.Expr) {
 	expr.SQL = m.DataTypeOf(field)
 
+	// SoftDelete columns hold 0 for live rows
+	if field.FieldType == softDeleteType {
+		expr.SQL += " DEFAULT 0"
+		return
+	}
+
 	if !field.NotNull {
 		expr.SQL += " NULL"
 	}
@@ -58,13 +86,7 @@ func (m Migrator) CreateTable(models ...interface{}) error {
 				columnSlice = append(columnSlice, fmt.Sprintf("\"%s\" %s", dbFieldName, m.FullDataTypeOf(field).SQL))
 			}
 
-			// Build primary index
-			primaryIndexSlice := make([]string, 0, len(stmt.Schema.PrimaryFieldDBNames))
-			primaryIndexSlice = append(primaryIndexSlice, stmt.Schema.PrimaryFieldDBNames...)
-
-			createTableSQL := fmt.Sprintf("CREATE FACT TABLE %s (%s) PRIMARY INDEX %s", stmt.Table, strings.Join(columnSlice, ","), strings.Join(primaryIndexSlice, ","))
-			fmt.Printf("%s", createTableSQL)
-
+			createTableSQL := fmt.Sprintf("CREATE %s TABLE %s (%s)%s", tableTypeOf(model), quotedTable(stmt), strings.Join(columnSlice, ","), tableIndexes(stmt))
 			return m.DB.Exec(createTableSQL).Error
 		}); err != nil {
 			return err
@@ -73,6 +95,68 @@ func (m Migrator) CreateTable(models ...interface{}) error {
 	return nil
 }
 
+// CreateTableAs creates the model's table from the query result (CREATE TABLE ... AS SELECT),
+// the model defines the table type, primary index and partitioning, while column types come from the query
+func (m Migrator) CreateTableAs(model interface{}, query *gorm.DB) error {
+	return m.RunWithValue(model, func(stmt *gorm.Statement) error {
+		columnSlice := make([]string, 0, len(stmt.Schema.DBNames))
+		for _, dbFieldName := range stmt.Schema.DBNames {
+			columnSlice = append(columnSlice, fmt.Sprintf("\"%s\"", dbFieldName))
+		}
+
+		createTableSQL := fmt.Sprintf("CREATE %s TABLE %s (%s)%s AS ?", tableTypeOf(model), quotedTable(stmt), strings.Join(columnSlice, ","), tableIndexes(stmt))
+		return m.DB.Exec(createTableSQL, query).Error
+	})
+}
+
+// quotedTable returns the quoted name of the statement's table, the expression set by Table("schema.table") is quoted already
+func quotedTable(stmt *gorm.Statement) string {
+	if stmt.TableExpr != nil {
+		return stmt.TableExpr.SQL
+	}
+	return stmt.Quote(stmt.Table)
+}
+
+// tableIndexes returns PRIMARY INDEX and PARTITION BY parts of CREATE TABLE statement.
+// Primary index consists of primary key fields, partitioning is defined by fields tagged with partition,
+// the tag value is used as partition expression when set, e.g. `gorm:"partition:EXTRACT(MONTH FROM created_at)"`
+func tableIndexes(stmt *gorm.Statement) string {
+	var sql string
+	if len(stmt.Schema.PrimaryFieldDBNames) > 0 {
+		columns := make([]string, 0, len(stmt.Schema.PrimaryFieldDBNames))
+		for _, dbName := range stmt.Schema.PrimaryFieldDBNames {
+			columns = append(columns, stmt.Quote(dbName))
+		}
+		sql += " PRIMARY INDEX " + strings.Join(columns, ",")
+	}
+
+	if partitions := partitionExpressions(stmt.Schema); len(partitions) > 0 {
+		// column names are quoted, expressions are kept as written in the tag
+		for idx, partition := range partitions {
+			if _, ok := stmt.Schema.FieldsByDBName[partition]; ok {
+				partitions[idx] = stmt.Quote(partition)
+			}
+		}
+		sql += " PARTITION BY " + strings.Join(partitions, ",")
+	}
+	return sql
+}
+
+// partitionExpressions returns the partition expressions of the fields tagged with partition
+func partitionExpressions(s *schema.Schema) []string {
+	partitionSlice := make([]string, 0)
+	for _, dbFieldName := range s.DBNames {
+		field := s.FieldsByDBName[dbFieldName]
+		if partition, ok := field.TagSettings["PARTITION"]; ok {
+			if partition == "PARTITION" {
+				partition = dbFieldName
+			}
+			partitionSlice = append(partitionSlice, partition)
+		}
+	}
+	return partitionSlice
+}
+
 func (m Migrator) HasTable(value interface{}) bool {
 	var count int64
 	err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
@@ -163,6 +247,192 @@ func (m Migrator) RenameIndex(dst interface{}, oldName, newName string) error {
 	return fmt.Errorf("RenameIndex is not implemented")
 }
 
+// GetIndexes returns the primary, aggregating and join indexes of the table from information_schema.indexes,
+// the index type is returned as the option of the index
 func (m Migrator) GetIndexes(dst interface{}) ([]gorm.Index, error) {
-	return nil, fmt.Errorf("GetIndexes is not implemented")
+	indexes := make([]gorm.Index, 0)
+	err := m.RunWithValue(dst, func(stmt *gorm.Statement) error {
+		rows, err := m.DB.Raw(
+			"SELECT index_name, index_type, index_definition FROM information_schema.indexes WHERE table_name = ?",
+			stmt.Table).Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var name, indexType, definition string
+			if err = rows.Scan(&name, &indexType, &definition); err != nil {
+				return err
+			}
+			indexes = append(indexes, migrator.Index{
+				TableName:       stmt.Table,
+				NameValue:       name,
+				ColumnList:      indexColumns(definition),
+				PrimaryKeyValue: sql.NullBool{Bool: strings.EqualFold(indexType, "primary"), Valid: true},
+				OptionValue:     strings.ToUpper(indexType),
+			})
+		}
+		return rows.Err()
+	})
+	return indexes, err
+}
+
+// indexColumns splits the definition of an index, e.g. [day, "country"], into its columns and expressions
+func indexColumns(definition string) []string {
+	definition = strings.TrimSpace(definition)
+	definition = strings.TrimPrefix(strings.TrimPrefix(definition, "("), "[")
+	definition = strings.TrimSuffix(strings.TrimSuffix(definition, ")"), "]")
+
+	var (
+		columns []string
+		depth   int
+		start   int
+	)
+	for idx, r := range definition + "," {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				if column := strings.Trim(strings.TrimSpace(definition[start:idx]), `"`); column != "" {
+					columns = append(columns, column)
+				}
+				start = idx + 1
+			}
+		}
+	}
+	return columns
+}
+
+// columnTypeBase is embedded by ColumnType under a name which doesn't hide the ColumnType method
+type columnTypeBase = migrator.ColumnType
+
+// ColumnType is a column of a table read from information_schema.columns
+type ColumnType struct {
+	columnTypeBase
+	PartitionValue sql.NullBool
+}
+
+// Length isn't reported by information_schema.columns
+func (ct ColumnType) Length() (length int64, ok bool) {
+	return 0, false
+}
+
+// DecimalSize returns the precision and the scale of DECIMAL columns
+func (ct ColumnType) DecimalSize() (precision int64, scale int64, ok bool) {
+	if ct.DataTypeValue.String != "DECIMAL" && ct.DataTypeValue.String != "NUMERIC" {
+		return 0, 0, false
+	}
+	_, err := fmt.Sscanf(ct.ColumnTypeValue.String[len(ct.DataTypeValue.String):], "(%d,%d)", &precision, &scale)
+	return precision, scale, err == nil
+}
+
+// ScanType returns the Go type of the column, e.g. []int32 for ARRAY(INT)
+func (ct ColumnType) ScanType() reflect.Type {
+	return scanTypeOf(ct.ColumnTypeValue.String)
+}
+
+// Partition returns whether the column is a part of the partition expression of the table
+func (ct ColumnType) Partition() (isPartition bool, ok bool) {
+	return ct.PartitionValue.Bool, ct.PartitionValue.Valid
+}
+
+// ColumnTypes returns the columns of the table from information_schema.columns in their order,
+// the columns are of ColumnType type
+func (m Migrator) ColumnTypes(dst interface{}) ([]gorm.ColumnType, error) {
+	columnTypes := make([]gorm.ColumnType, 0)
+	err := m.RunWithValue(dst, func(stmt *gorm.Statement) error {
+		rows, err := m.DB.Raw(
+			"SELECT column_name, data_type, is_nullable, is_in_primary_index, is_in_partition_expr "+
+				"FROM information_schema.columns WHERE table_name = ? ORDER BY ordinal_position",
+			stmt.Table).Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var name, dataType, nullable, primary, partition string
+			if err = rows.Scan(&name, &dataType, &nullable, &primary, &partition); err != nil {
+				return err
+			}
+			columnType := ColumnType{PartitionValue: sql.NullBool{Bool: partition == "YES", Valid: true}}
+			columnType.NameValue = sql.NullString{String: name, Valid: true}
+			columnType.DataTypeValue = sql.NullString{String: baseDataType(dataType), Valid: true}
+			columnType.ColumnTypeValue = sql.NullString{String: dataType, Valid: true}
+			columnType.NullableValue = sql.NullBool{Bool: nullable == "YES", Valid: true}
+			columnType.PrimaryKeyValue = sql.NullBool{Bool: primary == "YES", Valid: true}
+			columnTypes = append(columnTypes, columnType)
+		}
+		return rows.Err()
+	})
+	return columnTypes, err
+}
+
+// baseDataType returns the type name without its parameters, e.g. ARRAY for ARRAY(INT)
+func baseDataType(dataType string) string {
+	if idx := strings.IndexByte(dataType, '('); idx >= 0 {
+		dataType = dataType[:idx]
+	}
+	return strings.ToUpper(strings.TrimSpace(dataType))
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	bytesType     = reflect.TypeOf([]byte{})
+	interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+)
+
+// scanTypeOf maps Firebolt data type to a Go type, unknown types are mapped to interface{}
+func scanTypeOf(dataType string) reflect.Type {
+	dataType = strings.TrimSpace(dataType)
+	// nullability of array elements, e.g. ARRAY(INT NULL)
+	dataType = strings.TrimSuffix(strings.TrimSuffix(dataType, " NOT NULL"), " NULL")
+	switch name := baseDataType(dataType); name {
+	case "ARRAY":
+		// bare ARRAY and ARRAY() don't name the element type
+		elemType := interfaceType
+		if open := strings.IndexByte(dataType, '('); open >= 0 && strings.HasSuffix(dataType, ")") && open+1 < len(dataType)-1 {
+			elemType = scanTypeOf(dataType[open+1 : len(dataType)-1])
+		}
+		return reflect.SliceOf(elemType)
+	case "BOOLEAN", "BOOL":
+		return reflect.TypeOf(false)
+	case "INT", "INTEGER", "INT4":
+		return reflect.TypeOf(int32(0))
+	case "BIGINT", "LONG", "INT8":
+		return reflect.TypeOf(int64(0))
+	case "REAL", "FLOAT", "FLOAT4":
+		return reflect.TypeOf(float32(0))
+	case "DOUBLE", "DOUBLE PRECISION", "FLOAT8", "DECIMAL", "NUMERIC":
+		return reflect.TypeOf(float64(0))
+	case "TEXT", "STRING", "VARCHAR":
+		return reflect.TypeOf("")
+	case "DATE", "PGDATE", "TIMESTAMP", "TIMESTAMPNTZ", "TIMESTAMPTZ", "DATETIME", "TIMESTAMPEXT":
+		return timeType
+	case "BYTEA":
+		return bytesType
+	}
+	return interfaceType
+}
+
+// GetTableType returns the type of the table from information_schema.tables
+func (m Migrator) GetTableType(dst interface{}) (tableType TableType, err error) {
+	err = m.RunWithValue(dst, func(stmt *gorm.Statement) error {
+		var value string
+		if err := m.DB.Raw("SELECT table_type FROM information_schema.tables WHERE table_name = ?", stmt.Table).
+			Row().Scan(&value); err != nil {
+			return err
+		}
+		if strings.EqualFold(value, string(DimensionTable)) {
+			tableType = DimensionTable
+		} else {
+			tableType = FactTable
+		}
+		return nil
+	})
+	return
 }
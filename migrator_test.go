@@ -0,0 +1,71 @@
+package firebolt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type testDailyRevenue struct {
+	Day     time.Time `gorm:"primarykey;partition:EXTRACT(YEAR FROM day)"`
+	Country string    `gorm:"primarykey"`
+	Revenue float64
+}
+
+type testCountry struct {
+	Code string `gorm:"primarykey"`
+	Name string
+}
+
+func (testCountry) TableType() TableType {
+	return DimensionTable
+}
+
+type testPageView struct {
+	ID   int    `gorm:"primarykey"`
+	Site string `gorm:"partition"`
+	Path string
+}
+
+func TestCreateTableAs(t *testing.T) {
+	pool := &fakeConnPool{}
+	db := openTestDB(t, Config{}, pool, &gorm.Config{})
+
+	query := db.Table("events").
+		Select("date_trunc('day', created_at), country, sum(amount)").
+		Where("amount > ?", 0).
+		Group("1, 2")
+	assert.NoError(t, db.Migrator().(Migrator).CreateTableAs(&testDailyRevenue{}, query))
+
+	query = db.Table("raw_countries").Select("code, name")
+	assert.NoError(t, db.Migrator().(Migrator).CreateTableAs(&testCountry{}, query))
+
+	assert.Equal(t, []string{
+		`CREATE FACT TABLE "test_daily_revenues" ("day","country","revenue") PRIMARY INDEX "day","country" PARTITION BY EXTRACT(YEAR FROM day) AS SELECT date_trunc('day', created_at), country, sum(amount) FROM "events" WHERE amount > ? GROUP BY 1, 2`,
+		`CREATE DIMENSION TABLE "test_countries" ("code","name") PRIMARY INDEX "code" AS SELECT code, name FROM "raw_countries"`,
+	}, pool.execs)
+}
+
+func TestCreateTableType(t *testing.T) {
+	pool := &fakeConnPool{}
+	db := openTestDB(t, Config{}, pool, &gorm.Config{})
+
+	assert.NoError(t, db.Migrator().CreateTable(&testCountry{}))
+	assert.NoError(t, db.Table("analytics.countries").Migrator().CreateTable(&testCountry{}))
+	assert.Equal(t, []string{
+		`CREATE DIMENSION TABLE "test_countries" ("code" STRING NULL,"name" STRING NULL) PRIMARY INDEX "code"`,
+		`CREATE DIMENSION TABLE "analytics"."countries" ("code" STRING NULL,"name" STRING NULL) PRIMARY INDEX "code"`,
+	}, pool.execs)
+}
+
+func TestCreateTableIndexes(t *testing.T) {
+	pool := &fakeConnPool{}
+	db := openTestDB(t, Config{}, pool, &gorm.Config{})
+
+	assert.NoError(t, db.Migrator().CreateTable(&testPageView{}))
+	assert.Equal(t, []string{
+		`CREATE FACT TABLE "test_page_views" ("id" LONG NULL,"site" STRING NULL,"path" STRING NULL) PRIMARY INDEX "id" PARTITION BY "site"`,
+	}, pool.execs)
+}
@@ -0,0 +1,183 @@
+package firebolt
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/xitongsys/parquet-go/writer"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	parquetTypeBoolean    = "BOOLEAN"
+	parquetTypeInt64      = "INT64"
+	parquetTypeDouble     = "DOUBLE"
+	parquetTypeByteArray  = "BYTE_ARRAY"
+	parquetConvertedUTF8  = "UTF8"
+	parquetConvertedMicro = "TIMESTAMP_MICROS"
+)
+
+// parquetColumn is an optional column of a written parquet file
+type parquetColumn struct {
+	name          string
+	physicalType  string
+	convertedType string
+}
+
+// parquetColumnOf maps a GORM field to a parquet column
+func parquetColumnOf(field *schema.Field) *parquetColumn {
+	column := &parquetColumn{name: field.DBName}
+	switch field.DataType {
+	case schema.Bool:
+		column.physicalType = parquetTypeBoolean
+	case schema.Int, schema.Uint:
+		column.physicalType = parquetTypeInt64
+	case schema.Float:
+		column.physicalType = parquetTypeDouble
+	case schema.Time:
+		column.physicalType, column.convertedType = parquetTypeInt64, parquetConvertedMicro
+	case schema.Bytes:
+		column.physicalType = parquetTypeByteArray
+	default:
+		column.physicalType, column.convertedType = parquetTypeByteArray, parquetConvertedUTF8
+	}
+	return column
+}
+
+// metadata returns the column definition in the format of parquet-go CSV writer
+func (c *parquetColumn) metadata() string {
+	md := fmt.Sprintf("name=%s, type=%s, repetitiontype=OPTIONAL", c.name, c.physicalType)
+	if c.convertedType != "" {
+		md += ", convertedtype=" + c.convertedType
+	}
+	return md
+}
+
+// value converts value to the type parquet-go writes for the column, nil values are stored as nulls
+func (c *parquetColumn) value(value interface{}) (interface{}, error) {
+	value, err := driverValue(value)
+	if err != nil || value == nil {
+		return nil, err
+	}
+
+	switch c.physicalType {
+	case parquetTypeBoolean:
+		if rv := reflect.ValueOf(value); rv.Kind() == reflect.Bool {
+			return rv.Bool(), nil
+		}
+		return nil, fmt.Errorf("expected bool, got %T", value)
+	case parquetTypeInt64:
+		if c.convertedType == parquetConvertedMicro {
+			t, ok := value.(time.Time)
+			if !ok {
+				return nil, fmt.Errorf("expected time.Time, got %T", value)
+			}
+			return t.UnixMicro(), nil
+		}
+		return toInt64(value)
+	case parquetTypeDouble:
+		return toFloat64(value)
+	}
+	switch value := value.(type) {
+	case []byte:
+		return string(value), nil
+	case string:
+		return value, nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+// parquetWriter streams rows into a parquet file
+type parquetWriter struct {
+	writer  *writer.CSVWriter
+	columns []*parquetColumn
+}
+
+func newParquetWriter(w io.Writer, columns []*parquetColumn) (*parquetWriter, error) {
+	md := make([]string, 0, len(columns))
+	for _, column := range columns {
+		md = append(md, column.metadata())
+	}
+	pw, err := writer.NewCSVWriterFromWriter(md, w, 1)
+	if err != nil {
+		return nil, err
+	}
+	return &parquetWriter{writer: pw, columns: columns}, nil
+}
+
+// WriteRow appends a row, values should be ordered as writer columns
+func (pw *parquetWriter) WriteRow(values []interface{}) error {
+	if len(values) != len(pw.columns) {
+		return fmt.Errorf("expected %d values, got %d", len(pw.columns), len(values))
+	}
+	// the writer keeps the row until the row group is flushed
+	row := make([]interface{}, len(values))
+	for idx, value := range values {
+		var err error
+		if row[idx], err = pw.columns[idx].value(value); err != nil {
+			return fmt.Errorf("column %s: %w", pw.columns[idx].name, err)
+		}
+	}
+	return pw.writer.Write(row)
+}
+
+// Close flushes buffered rows and writes the file footer, it doesn't close the underlying writer
+func (pw *parquetWriter) Close() error {
+	return pw.writer.WriteStop()
+}
+
+// driverValue resolves driver.Valuer implementations and dereferences pointers,
+// nil is returned for NULL values
+func driverValue(value interface{}) (interface{}, error) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		if rv := reflect.ValueOf(value); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return nil, nil
+		}
+		var err error
+		if value, err = valuer.Value(); err != nil {
+			return nil, err
+		}
+	}
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil, nil
+	}
+	return rv.Interface(), nil
+}
+
+func toInt64(value interface{}) (int64, error) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), nil
+	case reflect.String:
+		return strconv.ParseInt(rv.String(), 10, 64)
+	}
+	return 0, fmt.Errorf("expected integer, got %T", value)
+}
+
+func toFloat64(value interface{}) (float64, error) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.String:
+		return strconv.ParseFloat(rv.String(), 64)
+	}
+	if i, err := toInt64(value); err == nil {
+		return float64(i), nil
+	}
+	return 0, fmt.Errorf("expected float, got %T", value)
+}
@@ -0,0 +1,105 @@
+package firebolt
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+// parquetFile is a parquet file read back by readParquet
+type parquetFile struct {
+	// schema describes the columns as "name TYPE REPETITION CONVERTED_TYPE"
+	schema    []string
+	rowGroups []int64
+	// rows hold int64, float64, bool, string for UTF8 and []byte values, nil for nulls
+	rows [][]interface{}
+}
+
+// readParquet reads the file with parquet-go column reader, so the tests check what other readers see
+func readParquet(t *testing.T, data []byte) (file parquetFile) {
+	t.Helper()
+	source, err := buffer.NewBufferFile(data)
+	if err != nil {
+		t.Fatalf("failed to open parquet file: %v", err)
+	}
+	pr, err := reader.NewParquetColumnReader(source, 1)
+	if err != nil {
+		t.Fatalf("failed to read parquet file: %v", err)
+	}
+	defer pr.ReadStop()
+
+	elements := pr.Footer.Schema[1:]
+	for idx, element := range elements {
+		// the reader renames the schema to Go identifiers, ExName is the name stored in the file
+		name := pr.SchemaHandler.Infos[idx+1].ExName
+		column := fmt.Sprintf("%s %s %s", name, element.GetType(), element.GetRepetitionType())
+		if element.IsSetConvertedType() {
+			column += " " + element.GetConvertedType().String()
+		}
+		file.schema = append(file.schema, column)
+	}
+	for _, rowGroup := range pr.Footer.RowGroups {
+		file.rowGroups = append(file.rowGroups, rowGroup.NumRows)
+	}
+
+	numRows := pr.GetNumRows()
+	file.rows = make([][]interface{}, numRows)
+	for idx := range file.rows {
+		file.rows[idx] = make([]interface{}, len(elements))
+	}
+	for column, element := range elements {
+		values, _, _, err := pr.ReadColumnByIndex(int64(column), numRows)
+		if err != nil || int64(len(values)) != numRows {
+			t.Fatalf("column %s has %d values, expected %d: %v", element.Name, len(values), numRows, err)
+		}
+		for row, value := range values {
+			if s, ok := value.(string); ok && element.GetType() == parquet.Type_BYTE_ARRAY && !element.IsSetConvertedType() {
+				value = []byte(s)
+			}
+			file.rows[row][column] = value
+		}
+	}
+	return file
+}
+
+func TestParquetWriterRowGroups(t *testing.T) {
+	var buf bytes.Buffer
+	pw, err := newParquetWriter(&buf, []*parquetColumn{
+		{name: "id", physicalType: parquetTypeInt64},
+		{name: "name", physicalType: parquetTypeByteArray, convertedType: parquetConvertedUTF8},
+		{name: "active", physicalType: parquetTypeBoolean},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	pw.writer.RowGroupSize = 64 * 1024
+	total := 100000
+	for idx := 0; idx < total; idx++ {
+		var name interface{}
+		if idx%3 == 0 {
+			name = fmt.Sprint("row ", idx)
+		}
+		assert.NoError(t, pw.WriteRow([]interface{}{idx, name, idx%2 == 0}))
+	}
+	assert.NoError(t, pw.Close())
+
+	file := readParquet(t, buf.Bytes())
+	assert.Equal(t, []string{"id INT64 OPTIONAL", "name BYTE_ARRAY OPTIONAL UTF8", "active BOOLEAN OPTIONAL"}, file.schema)
+	assert.Greater(t, len(file.rowGroups), 1)
+	if assert.Len(t, file.rows, total) {
+		for _, idx := range []int{0, 1, 2, total / 2, total - 1} {
+			var name interface{}
+			if idx%3 == 0 {
+				name = fmt.Sprint("row ", idx)
+			}
+			assert.Equal(t, []interface{}{int64(idx), name, idx%2 == 0}, file.rows[idx])
+		}
+	}
+
+	assert.EqualError(t, pw.WriteRow([]interface{}{1}), "expected 3 values, got 1")
+}
@@ -0,0 +1,46 @@
+package firebolt
+
+import (
+	"fmt"
+
+	"gorm.io/gorm/clause"
+)
+
+// QualifyClause is QUALIFY clause, it filters rows by the results of window functions
+type QualifyClause struct {
+	Exprs []clause.Expression
+	// err is reported by the statement instead of building the clause
+	err error
+}
+
+// Qualify returns QUALIFY clause for db.Clauses, query is either an SQL condition with args or a clause.Expression, e.g.
+//
+//	db.Clauses(firebolt.Qualify("? = 1", firebolt.RowNumber().PartitionBy("user_id").OrderBy("updated_at", true)))
+func Qualify(query interface{}, args ...interface{}) QualifyClause {
+	switch query := query.(type) {
+	case clause.Expression:
+		return QualifyClause{Exprs: []clause.Expression{query}}
+	case string:
+		return QualifyClause{Exprs: []clause.Expression{clause.Expr{SQL: query, Vars: args}}}
+	}
+	return QualifyClause{err: fmt.Errorf("QUALIFY condition of type %T is not supported", query)}
+}
+
+func (qualify QualifyClause) Name() string {
+	return ClauseQualify
+}
+
+func (qualify QualifyClause) Build(builder clause.Builder) {
+	clause.Where{Exprs: qualify.Exprs}.Build(builder)
+}
+
+func (qualify QualifyClause) MergeClause(c *clause.Clause) {
+	if existing, ok := c.Expression.(QualifyClause); ok {
+		exprs := make([]clause.Expression, 0, len(existing.Exprs)+len(qualify.Exprs))
+		qualify.Exprs = append(append(exprs, existing.Exprs...), qualify.Exprs...)
+		if qualify.err == nil {
+			qualify.err = existing.err
+		}
+	}
+	c.Expression = qualify
+}
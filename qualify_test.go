@@ -0,0 +1,48 @@
+package firebolt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/clause"
+)
+
+type testUserVersion struct {
+	ID        int
+	UserID    int
+	Name      string
+	UpdatedAt time.Time
+}
+
+func TestQualify(t *testing.T) {
+	db := dryRunDB(t)
+
+	var latest []testUserVersion
+	stmt := db.Where("name <> ?", "").
+		Clauses(Qualify("? = ?", RowNumber().PartitionBy("user_id").OrderBy("updated_at", true), 1)).
+		Order("user_id").Find(&latest).Statement
+	assert.Equal(t,
+		`SELECT * FROM "test_user_versions" WHERE name <> ? QUALIFY ROW_NUMBER() OVER (PARTITION BY "user_id" ORDER BY "updated_at" DESC) = ? ORDER BY user_id`,
+		stmt.SQL.String())
+	assert.Equal(t, []interface{}{"", 1}, stmt.Vars)
+}
+
+func TestQualifyAfterHaving(t *testing.T) {
+	db := dryRunDB(t)
+
+	stmt := db.Model(&testUserVersion{}).Select("user_id, count(*)").Group("user_id").Having("count(*) > ?", 1).
+		Clauses(Qualify("count(*) > 2"), Qualify(clause.Expr{SQL: "user_id > ?", Vars: []interface{}{10}})).Find(&[]testUserVersion{}).Statement
+	assert.Equal(t,
+		`SELECT user_id, count(*) FROM "test_user_versions" GROUP BY "user_id" HAVING count(*) > ? QUALIFY count(*) > 2 AND user_id > ?`,
+		stmt.SQL.String())
+	assert.Equal(t, []interface{}{1, 10}, stmt.Vars)
+}
+
+func TestQualifyUnsupportedCondition(t *testing.T) {
+	db := dryRunDB(t)
+
+	tx := db.Clauses(Qualify("user_id > 1"), Qualify(map[string]interface{}{"user_id": 1})).Find(&[]testUserVersion{})
+	assert.EqualError(t, tx.Error, "QUALIFY condition of type map[string]interface {} is not supported")
+	assert.NotContains(t, tx.Statement.SQL.String(), "QUALIFY")
+}
@@ -0,0 +1,110 @@
+package firebolt
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RetryPolicy retries idempotent statements failing with transient errors: queries and statements marked with Idempotent
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a statement including the first one, 3 by default
+	MaxAttempts int
+	// Backoff returns the delay before the next attempt after the failed one of the number,
+	// ExponentialBackoff(100*time.Millisecond, 5*time.Second) by default
+	Backoff func(attempt int) time.Duration
+	// Retryable tells whether a statement failed with the error can be retried, IsTransient by default
+	Retryable func(err error) bool
+}
+
+// ExponentialBackoff doubles the delay after every attempt starting from initial up to max,
+// the delay is randomized between its half and its full value
+func ExponentialBackoff(initial, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		delay := initial
+		for i := 1; i < attempt && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			delay = max
+		}
+		return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+	}
+}
+
+var defaultBackoff = ExponentialBackoff(100*time.Millisecond, 5*time.Second)
+
+// Firebolt error codes of network errors
+var transientErrorCodes = map[int]bool{209: true, 210: true}
+
+// IsTransient tells whether the error is likely to go away when the statement is retried:
+// connection errors, gateway errors, throttling and network errors reported by the engine
+func IsTransient(err error) bool {
+	if ClassifyError(err) == ErrorClassConnection {
+		return true
+	}
+	switch StatusCode(err) {
+	case 429, 502, 503, 504:
+		return true
+	}
+	return transientErrorCodes[ErrorCode(err)]
+}
+
+type idempotentKey struct{}
+
+// IdempotentClause marks a statement as safe to retry
+type IdempotentClause struct{}
+
+// Idempotent returns a clause marking the statement as safe to retry, e.g.
+//
+//	db.Clauses(firebolt.Idempotent()).Exec("INSERT INTO daily_totals SELECT ... WHERE NOT EXISTS (...)")
+func Idempotent() IdempotentClause {
+	return IdempotentClause{}
+}
+
+func (IdempotentClause) ModifyStatement(stmt *gorm.Statement) {
+	stmt.Context = context.WithValue(stmt.Context, idempotentKey{}, true)
+}
+
+func (IdempotentClause) Build(clause.Builder) {
+}
+
+// isIdempotent tells whether the statement can be retried
+func isIdempotent(ctx context.Context, query string) bool {
+	return ctx.Value(idempotentKey{}) != nil || isReadOnlySQL(query)
+}
+
+// run calls fn until it succeeds, the attempts are exhausted or it fails with an error which can't be retried
+func (policy *RetryPolicy) run(ctx context.Context, query string, fn func() error) error {
+	if policy == nil || !isIdempotent(ctx, query) {
+		return fn()
+	}
+	maxAttempts, backoff, retryable := policy.MaxAttempts, policy.Backoff, policy.Retryable
+	if maxAttempts <= 0 {
+		maxAttempts = 3
+	}
+	if backoff == nil {
+		backoff = defaultBackoff
+	}
+	if retryable == nil {
+		retryable = IsTransient
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= maxAttempts || ctx.Err() != nil || !retryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package firebolt
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestRetry(t *testing.T) {
+	unavailable := errors.New("request returned non ok status code: 502, <html>Bad Gateway</html>")
+	failures := map[string]int{}
+	fake := &fakeDriver{handler: func(query string, args []driver.NamedValue) (*fakeResult, error) {
+		if failures[query] > 0 {
+			failures[query]--
+			return nil, unavailable
+		}
+		return nil, nil
+	}}
+	retry := &RetryPolicy{Backoff: func(int) time.Duration { return 0 }}
+	db := openTestDB(t, Config{}, sql.OpenDB(sessionConnector{Connector: fake, retry: retry}), &gorm.Config{})
+
+	query := `SELECT * FROM "test_orders"`
+	failures[query] = 2
+	assert.NoError(t, db.Find(&[]testOrder{}).Error)
+	assert.Equal(t, []string{query, query, query}, fake.received())
+
+	failures[query] = 3
+	assert.ErrorIs(t, db.Find(&[]testOrder{}).Error, unavailable)
+	assert.Len(t, fake.received(), 6)
+
+	insert := "INSERT INTO test_orders SELECT * FROM staged_orders"
+	failures[insert] = 1
+	assert.ErrorIs(t, db.Exec(insert).Error, unavailable)
+	assert.Len(t, fake.received(), 7)
+
+	failures[insert] = 1
+	assert.NoError(t, db.Clauses(Idempotent()).Exec(insert).Error)
+	assert.Equal(t, []string{insert, insert}, fake.received()[7:])
+
+	syntax := errors.New("Code: 62. DB::Exception: Syntax error")
+	fake.handler = func(string, []driver.NamedValue) (*fakeResult, error) {
+		return nil, syntax
+	}
+	assert.ErrorIs(t, db.Find(&[]testOrder{}).Error, syntax)
+	assert.Len(t, fake.received(), 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	retry.Retryable = func(error) bool {
+		cancel()
+		return true
+	}
+	assert.Error(t, db.WithContext(ctx).Find(&[]testOrder{}).Error)
+	assert.Len(t, fake.received(), 11)
+}
+
+func TestIsTransient(t *testing.T) {
+	for err, transient := range map[error]bool{
+		errors.New("error during a request execution: dial tcp: connection refused"):     true,
+		errors.New("request returned non ok status code: 503, Service Unavailable"):      true,
+		errors.New("request returned non ok status code: 429, Too Many Requests"):        true,
+		errors.New("request returned non ok status code: 500, Code: 209. DB::Exception"): true,
+		errors.New("request returned non ok status code: 400, Code: 62. DB::Exception"):  false,
+		errors.New("request returned non ok status code: 401, Unauthorized"):             false,
+		context.Canceled: false,
+	} {
+		assert.Equal(t, transient, IsTransient(err), err.Error())
+	}
+
+	backoff := ExponentialBackoff(100*time.Millisecond, time.Second)
+	for attempt, max := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second} {
+		delay := backoff(attempt)
+		assert.True(t, delay >= max/2 && delay <= max, "attempt %d: %v", attempt, delay)
+	}
+}
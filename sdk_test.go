@@ -0,0 +1,104 @@
+package firebolt
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	fireboltgosdk "github.com/firebolt-db/firebolt-go-sdk"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeRequest is a statement sent to the engine of fakeFirebolt
+type fakeRequest struct {
+	query string
+	// params are the URL parameters of the request, including the settings of the connection
+	params url.Values
+}
+
+// fakeFirebolt serves Firebolt API and an engine over TLS, so connections opened from DSN by the SDK can be tested
+type fakeFirebolt struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []fakeRequest
+	handler  func(query string) (*fireboltgosdk.QueryResponse, error)
+	// connects is the number of connections the SDK opened, every connection looks up the account
+	connects int
+}
+
+// newFakeFirebolt starts the server and points the SDK to it for the rest of the test
+func newFakeFirebolt(t *testing.T, handler func(query string) (*fireboltgosdk.QueryResponse, error)) *fakeFirebolt {
+	server := &fakeFirebolt{handler: handler}
+	server.Server = httptest.NewTLSServer(http.HandlerFunc(server.serve))
+	t.Cleanup(server.Close)
+
+	// the SDK sends requests with the default transport, which has to trust the server's certificate
+	transport := http.DefaultTransport
+	http.DefaultTransport = server.Client().Transport
+	t.Cleanup(func() {
+		http.DefaultTransport = transport
+	})
+	t.Setenv("FIREBOLT_ENDPOINT", server.URL)
+	return server
+}
+
+// dsn returns DSN of the server's engine, an engine name with a dot is used by the SDK as the engine URL
+func (server *fakeFirebolt) dsn() string {
+	return "firebolt://client:secret@db/" + strings.TrimPrefix(server.URL, "https://")
+}
+
+func (server *fakeFirebolt) received() []fakeRequest {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return append([]fakeRequest{}, server.requests...)
+}
+
+func (server *fakeFirebolt) serve(w http.ResponseWriter, r *http.Request) {
+	var response interface{}
+	switch r.URL.Path {
+	case fireboltgosdk.ServiceAccountLoginURLSuffix:
+		response = fireboltgosdk.AuthenticationResponse{AccessToken: "token", ExpiresIn: 3600000, TokenType: "Bearer"}
+	case fireboltgosdk.DefaultAccountURL:
+		server.mu.Lock()
+		server.connects++
+		server.mu.Unlock()
+		response = map[string]interface{}{"account": map[string]string{"id": "account", "name": "account"}}
+	case "/":
+		body, _ := io.ReadAll(r.Body)
+		query := string(body)
+		server.mu.Lock()
+		server.requests = append(server.requests, fakeRequest{query: query, params: r.URL.Query()})
+		handler := server.handler
+		server.mu.Unlock()
+
+		result := &fireboltgosdk.QueryResponse{}
+		if handler != nil {
+			var err error
+			if result, err = handler(query); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		response = result
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// openSDKDB opens a gorm session connecting to the server with the SDK
+func openSDKDB(t *testing.T, config Config, server *fakeFirebolt) *gorm.DB {
+	config.DSN = server.dsn()
+	db, err := gorm.Open(New(config), &gorm.Config{DisableAutomaticPing: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("failed to open gorm session: %v", err)
+	}
+	return db
+}
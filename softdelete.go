@@ -0,0 +1,344 @@
+package firebolt
+
+import (
+	"errors"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// SoftDelete marks a row as deleted with the Unix time of the deletion, 0 for rows which aren't deleted, e.g.
+//
+//	type Order struct {
+//		ID        int `gorm:"primaryKey"`
+//		Day       time.Time `gorm:"partition:EXTRACT(MONTH FROM day)"`
+//		DeletedAt firebolt.SoftDelete
+//	}
+//
+// Delete doesn't update the row, it inserts a copy of it with the time of the deletion, a tombstone.
+// Queries and updates skip tombstones and the rows whose primary key has one, unless the statement is Unscoped.
+// Purge deletes both physically
+type SoftDelete int64
+
+var softDeleteType = reflect.TypeOf(SoftDelete(0))
+
+// Deleted tells whether the row is deleted
+func (sd SoftDelete) Deleted() bool {
+	return sd != 0
+}
+
+// Time returns the time of the deletion, zero time for rows which aren't deleted
+func (sd SoftDelete) Time() time.Time {
+	if sd == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(sd), 0)
+}
+
+const softDeleteEnabled = "firebolt:soft_delete"
+
+func (SoftDelete) QueryClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{SoftDeleteQueryClause{Field: f}}
+}
+
+// SoftDeleteQueryClause filters out deleted rows
+type SoftDeleteQueryClause struct {
+	Field *schema.Field
+}
+
+func (sd SoftDeleteQueryClause) Name() string {
+	return ""
+}
+
+func (sd SoftDeleteQueryClause) Build(clause.Builder) {
+}
+
+func (sd SoftDeleteQueryClause) MergeClause(*clause.Clause) {
+}
+
+func (sd SoftDeleteQueryClause) ModifyStatement(stmt *gorm.Statement) {
+	if _, ok := stmt.Clauses[softDeleteEnabled]; ok || stmt.Unscoped {
+		return
+	}
+	// a single OR condition would make the filter one of its alternatives
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok {
+			for _, expr := range where.Exprs {
+				if orCond, ok := expr.(clause.OrConditions); ok && len(orCond.Exprs) == 1 {
+					where.Exprs = []clause.Expression{clause.And(where.Exprs...)}
+					c.Expression = where
+					stmt.Clauses["WHERE"] = c
+					break
+				}
+			}
+		}
+	}
+
+	exprs := []clause.Expression{clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: sd.Field.DBName}, Value: 0}}
+	if len(sd.Field.Schema.PrimaryFieldDBNames) > 0 {
+		exprs = append(exprs, softDeleteNotDeleted{Field: sd.Field})
+	}
+	stmt.AddClause(clause.Where{Exprs: exprs})
+	stmt.Clauses[softDeleteEnabled] = clause.Clause{}
+}
+
+func (SoftDelete) UpdateClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{SoftDeleteUpdateClause{Field: f}}
+}
+
+// SoftDeleteUpdateClause keeps updates off deleted rows
+type SoftDeleteUpdateClause struct {
+	Field *schema.Field
+}
+
+func (sd SoftDeleteUpdateClause) Name() string {
+	return ""
+}
+
+func (sd SoftDeleteUpdateClause) Build(clause.Builder) {
+}
+
+func (sd SoftDeleteUpdateClause) MergeClause(*clause.Clause) {
+}
+
+func (sd SoftDeleteUpdateClause) ModifyStatement(stmt *gorm.Statement) {
+	if stmt.SQL.Len() == 0 && !stmt.Unscoped {
+		SoftDeleteQueryClause(sd).ModifyStatement(stmt)
+	}
+}
+
+func (SoftDelete) DeleteClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{SoftDeleteDeleteClause{Field: f}}
+}
+
+// SoftDeleteDeleteClause turns DELETE into INSERT of tombstones of the live rows it matches
+type SoftDeleteDeleteClause struct {
+	Field *schema.Field
+}
+
+func (sd SoftDeleteDeleteClause) Name() string {
+	return ""
+}
+
+func (sd SoftDeleteDeleteClause) Build(clause.Builder) {
+}
+
+func (sd SoftDeleteDeleteClause) MergeClause(*clause.Clause) {
+}
+
+const softDeleteTombstones = "firebolt:tombstones"
+
+// ModifyStatement replaces the clauses of DELETE, gorm's delete callback adds the conditions
+// of the primary keys and builds the statement
+func (sd SoftDeleteDeleteClause) ModifyStatement(stmt *gorm.Statement) {
+	if stmt.SQL.Len() > 0 || stmt.Unscoped {
+		return
+	}
+	if len(sd.Field.Schema.PrimaryFieldDBNames) == 0 {
+		_ = stmt.AddError(errors.New("SoftDelete requires a primary key, " + sd.Field.Schema.Name + " has none"))
+		return
+	}
+	deletedAt := SoftDelete(stmt.DB.NowFunc().Unix())
+	stmt.SetColumn(sd.Field.DBName, deletedAt, true)
+
+	SoftDeleteQueryClause(sd).ModifyStatement(stmt)
+	stmt.Clauses[softDeleteTombstones] = clause.Clause{Expression: softDeleteTombstone{Field: sd.Field, DeletedAt: deletedAt}}
+	stmt.BuildClauses = []string{softDeleteTombstones, "WHERE"}
+}
+
+// softDeleteTombstone builds INSERT of copies of the selected rows with the time of the deletion
+type softDeleteTombstone struct {
+	Field     *schema.Field
+	DeletedAt SoftDelete
+}
+
+func (tombstone softDeleteTombstone) Build(builder clause.Builder) {
+	table := clause.Table{Name: clause.CurrentTable}
+	names := tombstone.Field.Schema.DBNames
+	builder.WriteString("INSERT INTO ")
+	builder.WriteQuoted(table)
+	builder.WriteString(" (")
+	writeColumns(builder, columnsOf(names))
+	builder.WriteString(") SELECT ")
+	for idx, name := range names {
+		if idx > 0 {
+			builder.WriteByte(',')
+		}
+		if name == tombstone.Field.DBName {
+			builder.AddVar(builder, tombstone.DeletedAt)
+		} else {
+			builder.WriteQuoted(clause.Column{Name: name})
+		}
+	}
+	builder.WriteString(" FROM ")
+	builder.WriteQuoted(table)
+}
+
+// softDeleteNotDeleted filters out the rows whose primary key has a tombstone
+type softDeleteNotDeleted struct {
+	Field *schema.Field
+}
+
+func (sd softDeleteNotDeleted) Build(builder clause.Builder) {
+	names := sd.Field.Schema.PrimaryFieldDBNames
+	columns := make([]clause.Column, 0, len(names))
+	for _, name := range names {
+		columns = append(columns, clause.Column{Table: clause.CurrentTable, Name: name})
+	}
+	if len(columns) > 1 {
+		builder.WriteByte('(')
+		writeColumns(builder, columns)
+		builder.WriteByte(')')
+	} else {
+		builder.WriteQuoted(columns[0])
+	}
+	builder.WriteString(" NOT IN (SELECT ")
+	writeColumns(builder, columnsOf(names))
+	builder.WriteString(" FROM ")
+	builder.WriteQuoted(clause.Table{Name: clause.CurrentTable})
+	builder.WriteString(" WHERE ")
+	builder.WriteQuoted(clause.Column{Name: sd.Field.DBName})
+	builder.WriteString(" <> ")
+	builder.AddVar(builder, 0)
+	builder.WriteByte(')')
+}
+
+// Purge physically deletes the tombstones of the model's table matching the conditions of db and the rows
+// they were deleted from, batchSize partitions of the table at a time, so every DELETE only touches
+// the files of these partitions, e.g.
+//
+//	firebolt.Purge(db.Model(&Order{}).Where("deleted_at < ?", time.Now().AddDate(0, -1, 0).Unix()), 10)
+//
+// Tables without partitioning, or every partition when batchSize is 0, are purged with a single DELETE.
+// RowsAffected of the result is the total reported by the driver
+func Purge(db *gorm.DB, batchSize int) *gorm.DB {
+	tx := db.Session(&gorm.Session{})
+	model := db.Statement.Model
+	if model == nil {
+		model = db.Statement.Dest
+	}
+	if model == nil {
+		_ = tx.AddError(errors.New("Purge requires a model"))
+		return tx
+	}
+
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(model); err != nil {
+		_ = tx.AddError(err)
+		return tx
+	}
+	var deletedAt *schema.Field
+	for _, field := range stmt.Schema.Fields {
+		if field.FieldType == softDeleteType && field.DBName != "" {
+			deletedAt = field
+			break
+		}
+	}
+	if deletedAt == nil {
+		_ = tx.AddError(errors.New("Purge requires a model with a SoftDelete field, " + stmt.Schema.Name + " has none"))
+		return tx
+	}
+	if len(stmt.Schema.PrimaryFieldDBNames) == 0 {
+		_ = tx.AddError(errors.New("Purge requires a model with a primary key, " + stmt.Schema.Name + " has none"))
+		return tx
+	}
+
+	tombstones := tx.Unscoped().Where(clause.Neq{Column: clause.Column{Table: clause.CurrentTable, Name: deletedAt.DBName}, Value: 0}).
+		Session(&gorm.Session{})
+	partitions := partitionExpressions(stmt.Schema)
+	if len(partitions) == 0 || batchSize <= 0 {
+		return purgeTombstones(tombstones, stmt.Schema, model, nil)
+	}
+
+	keys, err := partitionKeys(tombstones, partitions)
+	if err != nil {
+		_ = tx.AddError(err)
+		return tx
+	}
+	for start := 0; start < len(keys); start += batchSize {
+		end := start + batchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		purged := purgeTombstones(tombstones, stmt.Schema, model, partitionCondition(partitions, keys[start:end]))
+		if purged.Error != nil {
+			_ = tx.AddError(purged.Error)
+			return tx
+		}
+		tx.RowsAffected += purged.RowsAffected
+	}
+	return tx
+}
+
+// purgeTombstones deletes the tombstones and the rows of their primary keys in the partitions of the condition
+func purgeTombstones(tombstones *gorm.DB, s *schema.Schema, model interface{}, partitions clause.Expression) *gorm.DB {
+	keys := tombstones.Select(s.PrimaryFieldDBNames)
+	rows := tombstones.Session(&gorm.Session{NewDB: true}).Unscoped()
+	if tombstones.Statement.Table != "" {
+		rows = rows.Table(tombstones.Statement.Table)
+	}
+	if partitions != nil {
+		keys, rows = keys.Where(partitions), rows.Where(partitions)
+	}
+
+	var columns interface{} = clause.Column{Table: clause.CurrentTable, Name: s.PrimaryFieldDBNames[0]}
+	if len(s.PrimaryFieldDBNames) > 1 {
+		names := make([]interface{}, 0, len(s.PrimaryFieldDBNames))
+		for _, name := range s.PrimaryFieldDBNames {
+			names = append(names, clause.Column{Table: clause.CurrentTable, Name: name})
+		}
+		columns = names
+	}
+	return rows.Where(clause.Expr{SQL: "? IN (?)", Vars: []interface{}{columns, keys}}).Delete(model)
+}
+
+// partitionCondition matches the partitions of the keys, NULL keys are matched with IS NULL
+func partitionCondition(partitions []string, keys [][]interface{}) clause.Expression {
+	conditions := make([]clause.Expression, 0, len(keys))
+	for _, key := range keys {
+		exprs := make([]clause.Expression, 0, len(partitions))
+		for idx, partition := range partitions {
+			if key[idx] == nil {
+				exprs = append(exprs, clause.Expr{SQL: partition + " IS NULL"})
+			} else {
+				exprs = append(exprs, clause.Expr{SQL: partition + " = ?", Vars: []interface{}{key[idx]}})
+			}
+		}
+		conditions = append(conditions, clause.And(exprs...))
+	}
+	// a single OR condition would be joined to the other conditions with OR
+	if len(conditions) == 1 {
+		return conditions[0]
+	}
+	return clause.Or(conditions...)
+}
+
+// partitionKeys returns the distinct values of the partition expressions of the rows matching the query
+func partitionKeys(tx *gorm.DB, partitions []string) ([][]interface{}, error) {
+	selects := make([]interface{}, 0, len(partitions))
+	for _, partition := range partitions {
+		selects = append(selects, partition)
+	}
+	rows, err := tx.Distinct(selects...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys [][]interface{}
+	for rows.Next() {
+		key := make([]interface{}, len(partitions))
+		dests := make([]interface{}, len(partitions))
+		for idx := range key {
+			dests[idx] = &key[idx]
+		}
+		if err = rows.Scan(dests...); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
@@ -0,0 +1,117 @@
+package firebolt
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type testPurchase struct {
+	ID        int       `gorm:"primarykey"`
+	Day       time.Time `gorm:"partition:EXTRACT(MONTH FROM day)"`
+	Amount    float64
+	DeletedAt SoftDelete
+}
+
+type testVisit struct {
+	ID        int `gorm:"primarykey"`
+	DeletedAt SoftDelete
+}
+
+type testPageVisit struct {
+	Page      string `gorm:"primarykey"`
+	Visitor   string `gorm:"primarykey"`
+	DeletedAt SoftDelete
+}
+
+func TestSoftDelete(t *testing.T) {
+	now := time.Date(2022, 8, 1, 12, 0, 0, 0, time.UTC)
+	db := openTestDB(t, Config{}, &fakeConnPool{}, &gorm.Config{DryRun: true, NowFunc: func() time.Time { return now }})
+
+	stmt := db.Where("amount > ?", 10).Or("amount < ?", 0).Find(&[]testPurchase{}).Statement
+	assert.Equal(t, `SELECT * FROM "test_purchases" WHERE (amount > ? OR amount < ?) AND "test_purchases"."deleted_at" = ? `+
+		`AND "test_purchases"."id" NOT IN (SELECT "id" FROM "test_purchases" WHERE "deleted_at" <> ?)`, stmt.SQL.String())
+	assert.Equal(t, []interface{}{10, 0, 0, 0}, stmt.Vars)
+
+	stmt = db.Unscoped().Find(&[]testPurchase{}).Statement
+	assert.Equal(t, `SELECT * FROM "test_purchases"`, stmt.SQL.String())
+
+	stmt = db.Model(&testPurchase{}).Where("id = ?", 1).Update("amount", 5).Statement
+	assert.Equal(t, `UPDATE "test_purchases" SET "amount"=? WHERE id = ? AND "test_purchases"."deleted_at" = ? `+
+		`AND "test_purchases"."id" NOT IN (SELECT "id" FROM "test_purchases" WHERE "deleted_at" <> ?)`, stmt.SQL.String())
+
+	purchase := testPurchase{ID: 1}
+	stmt = db.Delete(&purchase).Statement
+	// the deletion inserts a tombstone instead of updating the row
+	assert.Equal(t, `INSERT INTO "test_purchases" ("id","day","amount","deleted_at") SELECT "id","day","amount",? FROM "test_purchases" `+
+		`WHERE "test_purchases"."deleted_at" = ? AND "test_purchases"."id" NOT IN (SELECT "id" FROM "test_purchases" WHERE "deleted_at" <> ?) `+
+		`AND "test_purchases"."id" = ?`, stmt.SQL.String())
+	assert.Equal(t, []interface{}{SoftDelete(now.Unix()), 0, 0, 1}, stmt.Vars)
+	assert.True(t, purchase.DeletedAt.Deleted())
+	assert.Equal(t, now, purchase.DeletedAt.Time().UTC())
+	assert.True(t, SoftDelete(0).Time().IsZero())
+
+	stmt = db.Where("amount > ?", 100).Delete(&testPurchase{}).Statement
+	assert.Equal(t, `INSERT INTO "test_purchases" ("id","day","amount","deleted_at") SELECT "id","day","amount",? FROM "test_purchases" `+
+		`WHERE amount > ? AND "test_purchases"."deleted_at" = ? AND "test_purchases"."id" NOT IN (SELECT "id" FROM "test_purchases" WHERE "deleted_at" <> ?)`,
+		stmt.SQL.String())
+
+	// rows of composite primary keys are matched by tuples
+	stmt = db.Find(&[]testPageVisit{}).Statement
+	assert.Equal(t, `SELECT * FROM "test_page_visits" WHERE "test_page_visits"."deleted_at" = ? `+
+		`AND ("test_page_visits"."page","test_page_visits"."visitor") NOT IN (SELECT "page","visitor" FROM "test_page_visits" WHERE "deleted_at" <> ?)`,
+		stmt.SQL.String())
+
+	stmt = db.Unscoped().Delete(&testPurchase{ID: 1}).Statement
+	assert.Equal(t, `DELETE FROM "test_purchases" WHERE "test_purchases"."id" = ?`, stmt.SQL.String())
+
+	pool := &fakeConnPool{}
+	assert.NoError(t, openTestDB(t, Config{}, pool, &gorm.Config{}).Migrator().CreateTable(&testVisit{}))
+	if assert.Len(t, pool.execs, 1) {
+		assert.Contains(t, pool.execs[0], `"deleted_at" LONG DEFAULT 0`)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	db, fake := openFakeDB(t, Config{}, func(query string, args []driver.NamedValue) (*fakeResult, error) {
+		if query == `SELECT DISTINCT EXTRACT(MONTH FROM day) FROM "test_purchases" WHERE deleted_at < ? AND "test_purchases"."deleted_at" <> ?` {
+			return &fakeResult{columns: []string{"month"}, rows: [][]driver.Value{{int64(6)}, {nil}, {int64(7)}}}, nil
+		}
+		return nil, nil
+	})
+
+	// the partitions are purged two at a time, a NULL partition is matched with IS NULL
+	assert.NoError(t, Purge(db.Model(&testPurchase{}).Where("deleted_at < ?", 1656633600), 2).Error)
+	assert.NoError(t, Purge(db.Model(&testVisit{}), 2).Error)
+	tombstones := `"test_purchases"."id" IN (SELECT "id" FROM "test_purchases" WHERE deleted_at < ? AND "test_purchases"."deleted_at" <> ? AND `
+	assert.Equal(t, []string{
+		`SELECT DISTINCT EXTRACT(MONTH FROM day) FROM "test_purchases" WHERE deleted_at < ? AND "test_purchases"."deleted_at" <> ?`,
+		`DELETE FROM "test_purchases" WHERE (EXTRACT(MONTH FROM day) = ? OR EXTRACT(MONTH FROM day) IS NULL) AND ` +
+			tombstones + `(EXTRACT(MONTH FROM day) = ? OR EXTRACT(MONTH FROM day) IS NULL))`,
+		`DELETE FROM "test_purchases" WHERE EXTRACT(MONTH FROM day) = ? AND ` + tombstones + `EXTRACT(MONTH FROM day) = ?)`,
+		`DELETE FROM "test_visits" WHERE "test_visits"."id" IN (SELECT "id" FROM "test_visits" WHERE "test_visits"."deleted_at" <> ?)`,
+	}, fake.received())
+	assert.Equal(t, []driver.NamedValue{
+		{Ordinal: 1, Value: int64(7)}, {Ordinal: 2, Value: int64(1656633600)}, {Ordinal: 3, Value: int64(0)}, {Ordinal: 4, Value: int64(7)},
+	}, fake.args[2])
+
+	// every partition at once
+	assert.NoError(t, Purge(db.Model(&testPurchase{}), 0).Error)
+	assert.Equal(t, `DELETE FROM "test_purchases" WHERE "test_purchases"."id" IN (SELECT "id" FROM "test_purchases" WHERE "test_purchases"."deleted_at" <> ?)`,
+		fake.received()[4])
+
+	assert.EqualError(t, Purge(db.Model(&testOrder{}), 1).Error, "Purge requires a model with a SoftDelete field, testOrder has none")
+}
+
+func TestPurgeErrorKeepsDB(t *testing.T) {
+	db, fake := openFakeDB(t, Config{}, nil)
+
+	assert.EqualError(t, Purge(db, 1).Error, "Purge requires a model")
+	assert.NoError(t, db.Error)
+	assert.NoError(t, db.Find(&[]testVisit{}).Error)
+	assert.Equal(t, []string{`SELECT * FROM "test_visits" WHERE "test_visits"."deleted_at" = ? ` +
+		`AND "test_visits"."id" NOT IN (SELECT "id" FROM "test_visits" WHERE "deleted_at" <> ?)`}, fake.received())
+}
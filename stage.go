@@ -0,0 +1,207 @@
+package firebolt
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// Stage is a location the files for COPY FROM are uploaded to before loading
+type Stage interface {
+	// Location returns the URL Firebolt reads the staged file from
+	Location(name string) string
+	// Put uploads the file under the name
+	Put(ctx context.Context, name string, file io.ReadSeeker) error
+	// Remove deletes a previously uploaded file
+	Remove(ctx context.Context, name string) error
+	// CopyOptions returns additional COPY options needed to access the stage, e.g. CREDENTIALS,
+	// and the values of their ? placeholders, credentials are passed as Secret to be kept out of logs
+	CopyOptions() (string, []interface{})
+}
+
+// Secret is a statement parameter which is never logged or traced, e.g. a credential, Explain writes it as '***'
+type Secret string
+
+func (s Secret) Value() (driver.Value, error) {
+	return string(s), nil
+}
+
+// LocalStage stages files in a local directory, which has to be readable by the engine
+type LocalStage struct {
+	Dir string
+	// URL is the location of Dir as seen by the engine, Dir is used when empty
+	URL string
+}
+
+func (s LocalStage) Location(name string) string {
+	if s.URL != "" {
+		return strings.TrimSuffix(s.URL, "/") + "/" + name
+	}
+	return filepath.Join(s.Dir, name)
+}
+
+func (s LocalStage) Put(ctx context.Context, name string, file io.ReadSeeker) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	staged, err := os.Create(filepath.Join(s.Dir, name))
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(staged, file); err != nil {
+		staged.Close()
+		return err
+	}
+	return staged.Close()
+}
+
+func (s LocalStage) Remove(ctx context.Context, name string) error {
+	return os.Remove(filepath.Join(s.Dir, name))
+}
+
+func (s LocalStage) CopyOptions() (string, []interface{}) {
+	return "", nil
+}
+
+// S3Stage stages files in an S3 bucket, any S3 compatible storage like MinIO can be used through Endpoint
+type S3Stage struct {
+	Bucket string
+	// Prefix is prepended to the staged file names
+	Prefix string
+	Region string
+	// Endpoint overrides the default https://s3.<region>.amazonaws.com endpoint
+	Endpoint string
+	// PathStyle addresses the bucket as a part of the path instead of the host name, MinIO requires it
+	PathStyle bool
+	// RoleARN is the IAM role Firebolt assumes to read the staged files, the access key is used otherwise
+	RoleARN         string
+	AccessKeyID     string
+	SecretAccessKey string
+	// Client is used for the uploads, http.DefaultClient when nil
+	Client *http.Client
+}
+
+func (s S3Stage) key(name string) string {
+	if s.Prefix == "" {
+		return name
+	}
+	return path.Join(s.Prefix, name)
+}
+
+func (s S3Stage) Location(name string) string {
+	return fmt.Sprintf("s3://%s/%s", s.Bucket, s.key(name))
+}
+
+func (s S3Stage) Put(ctx context.Context, name string, file io.ReadSeeker) error {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return s.do(ctx, http.MethodPut, name, io.NopCloser(file), size, hex.EncodeToString(hash.Sum(nil)))
+}
+
+func (s S3Stage) Remove(ctx context.Context, name string) error {
+	emptyHash := sha256.Sum256(nil)
+	return s.do(ctx, http.MethodDelete, name, nil, 0, hex.EncodeToString(emptyHash[:]))
+}
+
+func (s S3Stage) CopyOptions() (string, []interface{}) {
+	if s.RoleARN != "" {
+		return "CREDENTIALS = (AWS_ROLE_ARN = ?)", []interface{}{s.RoleARN}
+	}
+	if s.AccessKeyID == "" {
+		return "", nil
+	}
+	return "CREDENTIALS = (AWS_KEY_ID = ? AWS_SECRET_KEY = ?)", []interface{}{Secret(s.AccessKeyID), Secret(s.SecretAccessKey)}
+}
+
+// do sends a request for the object signed with AWS signature version 4
+func (s S3Stage) do(ctx context.Context, method, name string, body io.ReadCloser, size int64, payloadHash string) error {
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", s.Region)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	if s.PathStyle {
+		u.Path = "/" + s.Bucket + "/" + s.key(name)
+	} else {
+		u.Host = s.Bucket + "." + u.Host
+		u.Path = "/" + s.key(name)
+	}
+	u.RawPath = awsURIEncode(u.Path)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if err = s.sign(req, payloadHash, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s %s returned status code %d: %s", method, s.Location(name), resp.StatusCode, message)
+	}
+	return nil
+}
+
+// sign signs the request with AWS signature version 4
+func (s S3Stage) sign(req *http.Request, payloadHash string, now time.Time) error {
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	credentials := aws.Credentials{AccessKeyID: s.AccessKeyID, SecretAccessKey: s.SecretAccessKey}
+	return v4.NewSigner().SignHTTP(req.Context(), credentials, req, payloadHash, "s3", s.Region, now, func(options *v4.SignerOptions) {
+		// S3 signs the path as it is sent, awsURIEncode has escaped it already
+		options.DisableURIPathEscaping = true
+	})
+}
+
+// awsURIEncode escapes everything except unreserved characters and slashes, as required by AWS signatures
+func awsURIEncode(path string) string {
+	var encoded strings.Builder
+	for _, b := range []byte(path) {
+		if ('A' <= b && b <= 'Z') || ('a' <= b && b <= 'z') || ('0' <= b && b <= '9') || strings.IndexByte("-_.~/", b) >= 0 {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return encoded.String()
+}
+
+// quoteString returns the SQL string literal of s
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), "'", `\'`) + "'"
+}
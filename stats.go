@@ -0,0 +1,128 @@
+package firebolt
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const queryStatsKey = "firebolt:query_stats"
+
+// QueryStats are statistics of the statements executed by a call, e.g. Find or Exec
+type QueryStats struct {
+	// QueryID is the Firebolt query ID, the ID of the last statement when the call executed several
+	QueryID string
+	// Elapsed is the execution time reported by the server,
+	// or the duration of the call measured by the client when the server didn't report statistics
+	Elapsed   time.Duration
+	RowsRead  int64
+	BytesRead int64
+	// Statements is the number of statements the server reported statistics for
+	Statements int
+}
+
+// Reported tells whether the statistics were reported by the server
+func (stats QueryStats) Reported() bool {
+	return stats.Statements > 0
+}
+
+// statsCollector accumulates statistics reported for the statements of a call
+type statsCollector struct {
+	mu    sync.Mutex
+	stats QueryStats
+	begin time.Time
+	end   time.Time
+	// ctx is the statement context carrying the collector, parent is the context it replaced
+	ctx, parent context.Context
+}
+
+type statsContextKey struct{}
+
+// ReportQueryStats is called by a driver or a connection pool set with Dialector.Conn
+// with the statistics of a statement executed with ctx
+func ReportQueryStats(ctx context.Context, stats QueryStats) {
+	collector, ok := ctx.Value(statsContextKey{}).(*statsCollector)
+	if !ok {
+		return
+	}
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+
+	if stats.QueryID != "" {
+		collector.stats.QueryID = stats.QueryID
+	}
+	collector.stats.Elapsed += stats.Elapsed
+	collector.stats.RowsRead += stats.RowsRead
+	collector.stats.BytesRead += stats.BytesRead
+	if stats.Statements == 0 {
+		stats.Statements = 1
+	}
+	collector.stats.Statements += stats.Statements
+}
+
+// StatsFrom returns statistics of the statements executed by the last call of db, e.g.
+//
+//	tx := db.Where("amount > ?", 100).Find(&orders)
+//	stats, ok := firebolt.StatsFrom(tx)
+func StatsFrom(db *gorm.DB) (QueryStats, bool) {
+	value, ok := db.InstanceGet(queryStatsKey)
+	if !ok {
+		return QueryStats{}, false
+	}
+	collector := value.(*statsCollector)
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+
+	stats := collector.stats
+	if !stats.Reported() {
+		end := collector.end
+		if end.IsZero() {
+			end = time.Now()
+		}
+		stats.Elapsed = end.Sub(collector.begin)
+	}
+	return stats, true
+}
+
+func (dialector Dialector) registerStatsCallbacks(db *gorm.DB) error {
+	return registerAround(db, "firebolt:stats", func(string) func(*gorm.DB) {
+		return beforeStats
+	}, func(string) func(*gorm.DB) {
+		return dialector.afterStats
+	})
+}
+
+func beforeStats(db *gorm.DB) {
+	collector := &statsCollector{begin: time.Now(), parent: db.Statement.Context}
+	if collector.parent == nil {
+		collector.parent = context.Background()
+	}
+	collector.ctx = context.WithValue(collector.parent, statsContextKey{}, collector)
+	db.Statement.Context = collector.ctx
+	db.InstanceSet(queryStatsKey, collector)
+}
+
+func (dialector Dialector) afterStats(db *gorm.DB) {
+	value, ok := db.InstanceGet(queryStatsKey)
+	if !ok {
+		return
+	}
+	collector := value.(*statsCollector)
+	collector.mu.Lock()
+	collector.end = time.Now()
+	collector.mu.Unlock()
+	if db.Statement.Context == collector.ctx {
+		db.Statement.Context = collector.parent
+	}
+
+	if dialector.SlowQueryThreshold <= 0 || db.Statement.SQL.Len() == 0 {
+		return
+	}
+	if stats, _ := StatsFrom(db); stats.Elapsed >= dialector.SlowQueryThreshold {
+		db.Logger.Warn(db.Statement.Context, "slow query [%.3fms] [rows read:%d] [bytes read:%d] [query id:%s] %s",
+			float64(stats.Elapsed.Nanoseconds())/1e6, stats.RowsRead, stats.BytesRead, stats.QueryID,
+			db.Dialector.Explain(db.Statement.SQL.String(), db.Statement.Vars...))
+	}
+}
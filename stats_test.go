@@ -0,0 +1,114 @@
+package firebolt
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	fireboltgosdk "github.com/firebolt-db/firebolt-go-sdk"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type testLogWriter struct {
+	lines []string
+}
+
+func (w *testLogWriter) Printf(format string, args ...interface{}) {
+	w.lines = append(w.lines, fmt.Sprintf(format, args...))
+}
+
+func reportingHandler(query string, args []driver.NamedValue) (*fakeResult, error) {
+	switch {
+	case strings.HasPrefix(query, "SELECT"):
+		return &fakeResult{
+			columns: []string{"id", "customer", "amount"},
+			rows:    [][]driver.Value{{int64(1), "acme", 10.0}},
+			stats:   &QueryStats{QueryID: "query-1", Elapsed: 25 * time.Millisecond, RowsRead: 1000, BytesRead: 64000},
+		}, nil
+	case strings.HasPrefix(query, "INSERT"):
+		return &fakeResult{stats: &QueryStats{QueryID: fmt.Sprintf("insert-%d", len(args)), Elapsed: time.Millisecond, RowsRead: int64(len(args) / 3)}}, nil
+	}
+	return nil, nil
+}
+
+func TestStatsFrom(t *testing.T) {
+	db, _ := openFakeDB(t, Config{MaxInsertRows: 2}, reportingHandler)
+
+	tx := db.Where("customer = ?", "acme").Find(&[]testOrder{})
+	assert.NoError(t, tx.Error)
+	stats, ok := StatsFrom(tx)
+	assert.True(t, ok)
+	assert.Equal(t, QueryStats{QueryID: "query-1", Elapsed: 25 * time.Millisecond, RowsRead: 1000, BytesRead: 64000, Statements: 1}, stats)
+	assert.True(t, stats.Reported())
+
+	tx = db.Create(&[]testOrder{{ID: 1}, {ID: 2}, {ID: 3}})
+	assert.NoError(t, tx.Error)
+	stats, _ = StatsFrom(tx)
+	assert.Equal(t, 2, stats.Statements)
+	assert.Equal(t, int64(3), stats.RowsRead)
+	assert.Equal(t, 2*time.Millisecond, stats.Elapsed)
+
+	tx = db.Exec("SET use_standard_sql = 1")
+	assert.NoError(t, tx.Error)
+	stats, ok = StatsFrom(tx)
+	assert.True(t, ok)
+	assert.False(t, stats.Reported())
+	assert.Greater(t, stats.Elapsed, time.Duration(0))
+
+	_, ok = StatsFrom(db)
+	assert.False(t, ok)
+}
+
+func TestSlowQueryLog(t *testing.T) {
+	db, _ := openFakeDB(t, Config{SlowQueryThreshold: 20 * time.Millisecond}, reportingHandler)
+	writer := &testLogWriter{}
+	db = db.Session(&gorm.Session{Logger: logger.New(writer, logger.Config{LogLevel: logger.Warn})})
+
+	assert.NoError(t, db.Create(&testOrder{ID: 1}).Error)
+	assert.Empty(t, writer.lines)
+
+	assert.NoError(t, db.Where("customer = ?", "acme").Find(&[]testOrder{}).Error)
+	if assert.Len(t, writer.lines, 1) {
+		assert.Contains(t, writer.lines[0], `slow query [25.000ms] [rows read:1000] [bytes read:64000] [query id:query-1] SELECT * FROM "test_orders" WHERE customer = 'acme'`)
+	}
+}
+
+func TestStatsFromSDK(t *testing.T) {
+	server := newFakeFirebolt(t, func(query string) (*fireboltgosdk.QueryResponse, error) {
+		if strings.HasPrefix(query, "SELECT") {
+			return &fireboltgosdk.QueryResponse{
+				Query:      map[string]interface{}{"query_id": "query-1"},
+				Meta:       []fireboltgosdk.Column{{Name: "id", Type: "long"}, {Name: "customer", Type: "text"}, {Name: "amount", Type: "double"}},
+				Data:       [][]interface{}{{1, "acme", 10.0}},
+				Rows:       1,
+				Statistics: map[string]interface{}{"elapsed": 0.025, "rows_read": 1000, "bytes_read": 64000},
+			}, nil
+		}
+		return &fireboltgosdk.QueryResponse{}, nil
+	})
+	db := openSDKDB(t, Config{}, server)
+
+	// the SDK doesn't expose the statistics of its responses, the duration of the call is measured instead
+	var orders []testOrder
+	tx := db.Where("customer = ?", "acme").Find(&orders)
+	assert.NoError(t, tx.Error)
+	assert.Equal(t, []testOrder{{ID: 1, Customer: "acme", Amount: 10}}, orders)
+	stats, _ := StatsFrom(tx)
+	assert.False(t, stats.Reported())
+	assert.Greater(t, stats.Elapsed, time.Duration(0))
+
+	tx = db.Create(&[]testOrder{{ID: 1}, {ID: 2}})
+	assert.NoError(t, tx.Error)
+	stats, _ = StatsFrom(tx)
+	assert.False(t, stats.Reported())
+	assert.Greater(t, stats.Elapsed, time.Duration(0))
+
+	assert.Equal(t, []string{
+		`SELECT * FROM "test_orders" WHERE customer = 'acme'`,
+		`INSERT INTO "test_orders" ("customer","amount","id") VALUES ('',0,1),('',0,2)`,
+	}, []string{server.received()[0].query, server.received()[1].query})
+}
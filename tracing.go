@@ -0,0 +1,161 @@
+package firebolt
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	tracerName  = "github.com/firebolt-db/firebolt-gorm"
+	tracingSpan = "firebolt:tracing_span"
+)
+
+const (
+	// RowsAffectedKey is the span attribute holding the number of rows affected by the statement
+	RowsAffectedKey = attribute.Key("db.rows_affected")
+	// QueryIDKey is the span attribute holding Firebolt query ID
+	QueryIDKey = attribute.Key("firebolt.query_id")
+	// RowsReadKey and BytesReadKey are the span attributes holding the statistics reported by the server
+	RowsReadKey  = attribute.Key("firebolt.rows_read")
+	BytesReadKey = attribute.Key("firebolt.bytes_read")
+)
+
+// TracingPlugin is a gorm.Plugin creating an OpenTelemetry span for every create, query, update, delete, row and raw call, e.g.
+//
+//	err := db.Use(&firebolt.TracingPlugin{RedactSQL: true})
+type TracingPlugin struct {
+	// TracerProvider creates the tracer, the global provider is used when nil
+	TracerProvider trace.TracerProvider
+	// Attributes are added to every span, e.g. the engine name
+	Attributes []attribute.KeyValue
+	// RedactSQL records the statement with placeholders instead of the bound values
+	RedactSQL bool
+
+	tracer trace.Tracer
+}
+
+// callbackRegisterer registers a callback at the position it was created for
+type callbackRegisterer interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
+
+// tracedCall is the span of a call and the context it was started from
+type tracedCall struct {
+	span   trace.Span
+	parent context.Context
+}
+
+func (plugin *TracingPlugin) Name() string {
+	return "firebolt:tracing"
+}
+
+func (plugin *TracingPlugin) Initialize(db *gorm.DB) error {
+	provider := plugin.TracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	plugin.tracer = provider.Tracer(tracerName)
+
+	return registerAround(db, "firebolt:tracing", func(operation string) func(*gorm.DB) {
+		return plugin.before("firebolt." + operation)
+	}, func(string) func(*gorm.DB) {
+		return plugin.after
+	})
+}
+
+// registerAround registers callbacks running before and after all the other callbacks
+// of create, query, update, delete, row and raw operations, after is optional
+func registerAround(db *gorm.DB, name string, before, after func(operation string) func(*gorm.DB)) error {
+	callback := db.Callback()
+	processors := []struct {
+		operation     string
+		before, after callbackRegisterer
+	}{
+		{"create", callback.Create().Before("*"), callback.Create().After("*")},
+		{"query", callback.Query().Before("*"), callback.Query().After("*")},
+		{"update", callback.Update().Before("*"), callback.Update().After("*")},
+		{"delete", callback.Delete().Before("*"), callback.Delete().After("*")},
+		{"row", callback.Row().Before("*"), callback.Row().After("*")},
+		{"raw", callback.Raw().Before("*"), callback.Raw().After("*")},
+	}
+	for _, p := range processors {
+		if err := p.before.Register(name+"_before_"+p.operation, before(p.operation)); err != nil {
+			return err
+		}
+		if after == nil {
+			continue
+		}
+		if err := p.after.Register(name+"_after_"+p.operation, after(p.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (plugin *TracingPlugin) before(name string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		parent := db.Statement.Context
+		if parent == nil {
+			parent = context.Background()
+		}
+		ctx, span := plugin.tracer.Start(parent, name, trace.WithSpanKind(trace.SpanKindClient))
+		db.Statement.Context = ctx
+		db.InstanceSet(tracingSpan, tracedCall{span: span, parent: parent})
+	}
+}
+
+func (plugin *TracingPlugin) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(tracingSpan)
+	if !ok {
+		return
+	}
+	call := value.(tracedCall)
+	db.Statement.Context = call.parent
+	defer call.span.End()
+
+	attributes := []attribute.KeyValue{semconv.DBSystemKey.String("firebolt")}
+	if db.Statement.Table != "" {
+		attributes = append(attributes, semconv.DBSQLTableKey.String(db.Statement.Table))
+	}
+	if sql := db.Statement.SQL.String(); sql != "" {
+		if !plugin.RedactSQL {
+			sql = db.Dialector.Explain(sql, db.Statement.Vars...)
+		}
+		attributes = append(attributes,
+			semconv.DBStatementKey.String(sql),
+			semconv.DBOperationKey.String(sqlOperation(sql)),
+		)
+	}
+	if db.RowsAffected >= 0 {
+		attributes = append(attributes, RowsAffectedKey.Int64(db.RowsAffected))
+	}
+	if stats, ok := StatsFrom(db); ok && stats.Reported() {
+		attributes = append(attributes, RowsReadKey.Int64(stats.RowsRead), BytesReadKey.Int64(stats.BytesRead))
+		if stats.QueryID != "" {
+			attributes = append(attributes, QueryIDKey.String(stats.QueryID))
+		}
+	}
+	call.span.SetAttributes(append(attributes, plugin.Attributes...)...)
+
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		call.span.RecordError(db.Error)
+		call.span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
+
+// sqlOperation returns the first keyword of the statement, e.g. SELECT
+func sqlOperation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
@@ -0,0 +1,98 @@
+package firebolt
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func spanAttributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attributes := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes {
+		attributes[kv.Key] = kv.Value
+	}
+	return attributes
+}
+
+func TestTracingPlugin(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	db, _ := openFakeDB(t, Config{}, func(query string, args []driver.NamedValue) (*fakeResult, error) {
+		if strings.HasPrefix(query, "DELETE") {
+			return nil, errors.New("DELETE is not supported")
+		}
+		if strings.HasPrefix(query, "SELECT * ") {
+			return &fakeResult{stats: &QueryStats{QueryID: "query-1", RowsRead: 10, BytesRead: 640}}, nil
+		}
+		return nil, nil
+	})
+	assert.NoError(t, db.Use(&TracingPlugin{
+		TracerProvider: provider,
+		Attributes:     []attribute.KeyValue{attribute.String("firebolt.engine", "test_engine")},
+	}))
+
+	assert.NoError(t, db.Create(&testOrder{ID: 1, Customer: "acme", Amount: 10}).Error)
+	assert.NoError(t, db.Where("customer = ?", "acme").Find(&[]testOrder{}).Error)
+	assert.NoError(t, db.Model(&testOrder{}).Where("id = ?", 1).Update("amount", 20).Error)
+	assert.Error(t, db.Where("id = ?", 1).Delete(&testOrder{}).Error)
+	rows, err := db.Model(&testOrder{}).Select("id").Rows()
+	assert.NoError(t, err)
+	rows.Close()
+	assert.NoError(t, db.Exec("SET use_standard_sql = 1").Error)
+
+	spans := exporter.GetSpans()
+	var names []string
+	for _, span := range spans {
+		names = append(names, span.Name)
+		assert.Equal(t, trace.SpanKindClient, span.SpanKind)
+		assert.Equal(t, "firebolt", spanAttributes(span)["db.system"].AsString())
+		assert.Equal(t, "test_engine", spanAttributes(span)["firebolt.engine"].AsString())
+	}
+	assert.Equal(t, []string{"firebolt.create", "firebolt.query", "firebolt.update", "firebolt.delete", "firebolt.row", "firebolt.raw"}, names)
+
+	query := spanAttributes(spans[1])
+	assert.Equal(t, `SELECT * FROM "test_orders" WHERE customer = 'acme'`, query["db.statement"].AsString())
+	assert.Equal(t, "SELECT", query["db.operation"].AsString())
+	assert.Equal(t, "test_orders", query["db.sql.table"].AsString())
+	assert.Equal(t, "query-1", query["firebolt.query_id"].AsString())
+	assert.Equal(t, int64(640), query["firebolt.bytes_read"].AsInt64())
+	assert.Equal(t, int64(1), spanAttributes(spans[0])["db.rows_affected"].AsInt64())
+	assert.NotContains(t, spanAttributes(spans[0]), QueryIDKey)
+
+	assert.Equal(t, codes.Error, spans[3].Status.Code)
+	assert.Equal(t, "DELETE is not supported", spans[3].Status.Description)
+	assert.Len(t, spans[3].Events, 1)
+	assert.Equal(t, codes.Unset, spans[1].Status.Code)
+
+	assert.Equal(t, "SET", spanAttributes(spans[5])["db.operation"].AsString())
+}
+
+func TestTracingPluginRedactSQL(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	db, _ := openFakeDB(t, Config{}, nil)
+	assert.NoError(t, db.Use(&TracingPlugin{
+		TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)),
+		RedactSQL:      true,
+	}))
+
+	ctx, parent := sdktrace.NewTracerProvider().Tracer("test").Start(db.Statement.Context, "parent")
+	tx := db.WithContext(ctx).Where("customer = ?", "acme")
+	assert.NoError(t, tx.Find(&[]testOrder{}).Error)
+	assert.Equal(t, ctx, tx.Statement.Context)
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, `SELECT * FROM "test_orders" WHERE customer = ?`, spanAttributes(spans[0])["db.statement"].AsString())
+		assert.Equal(t, parent.SpanContext().TraceID(), spans[0].SpanContext.TraceID())
+		assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent.SpanID())
+	}
+}
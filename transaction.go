@@ -0,0 +1,39 @@
+package firebolt
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrTransactionsUnsupported is returned by Begin unless Config.Transactions is set with a Dialector.Conn supporting transactions
+var ErrTransactionsUnsupported = errors.New("firebolt: transactions aren't supported, set Config.Transactions with a driver carrying transactions between statements")
+
+// ErrSavePointsUnsupported is returned by SavePoint and RollbackTo when transactions are enabled, Firebolt has no savepoints,
+// nested transactions run in the outer one with gorm.Config.DisableNestedTransaction
+var ErrSavePointsUnsupported = errors.New("firebolt: savepoints aren't supported by Firebolt")
+
+func (dialector Dialector) SavePoint(tx *gorm.DB, name string) error {
+	if !dialector.Transactions {
+		return ErrTransactionsUnsupported
+	}
+	return ErrSavePointsUnsupported
+}
+
+func (dialector Dialector) RollbackTo(tx *gorm.DB, name string) error {
+	if !dialector.Transactions {
+		return ErrTransactionsUnsupported
+	}
+	return ErrSavePointsUnsupported
+}
+
+func (c *sessionConn) Begin() (driver.Tx, error) {
+	return nil, ErrTransactionsUnsupported
+}
+
+// BeginTx fails, the connections opened from DSN send every statement as a request of its own
+func (c *sessionConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return nil, ErrTransactionsUnsupported
+}
@@ -0,0 +1,116 @@
+package firebolt
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestTransactionsUnsupported(t *testing.T) {
+	fake := &fakeDriver{}
+	db := openTestDB(t, Config{}, sql.OpenDB(sessionConnector{Connector: fake}), &gorm.Config{})
+	assert.True(t, db.SkipDefaultTransaction)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&testOrder{ID: 1}).Error
+	})
+	assert.ErrorIs(t, err, ErrTransactionsUnsupported)
+	assert.ErrorIs(t, db.Begin().Error, ErrTransactionsUnsupported)
+	assert.ErrorIs(t, db.SavePoint("sp1").Error, ErrTransactionsUnsupported)
+	assert.ErrorIs(t, db.RollbackTo("sp1").Error, ErrTransactionsUnsupported)
+	assert.Empty(t, fake.received())
+}
+
+// fakeTxDriver opens connections with transactions, like a driver carrying the transaction between its requests would
+type fakeTxDriver struct {
+	*fakeDriver
+}
+
+func (d fakeTxDriver) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := d.fakeDriver.Connect(ctx)
+	return fakeTxConn{conn.(*fakeConn)}, err
+}
+
+type fakeTxConn struct {
+	*fakeConn
+}
+
+func (c fakeTxConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if opts.ReadOnly {
+		return nil, errors.New("read only transactions aren't supported")
+	}
+	_, err := c.driver.run("BEGIN", nil)
+	return fakeTx{c.fakeConn}, err
+}
+
+type fakeTx struct {
+	*fakeConn
+}
+
+func (tx fakeTx) Commit() error {
+	_, err := tx.driver.run("COMMIT", nil)
+	return err
+}
+
+func (tx fakeTx) Rollback() error {
+	_, err := tx.driver.run("ROLLBACK", nil)
+	return err
+}
+
+func TestTransactions(t *testing.T) {
+	fake := &fakeDriver{}
+	db := openTestDB(t, Config{Transactions: true}, sql.OpenDB(fakeTxDriver{fake}), &gorm.Config{})
+	assert.False(t, db.SkipDefaultTransaction)
+
+	failed := errors.New("failed")
+	assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return tx.Exec("DELETE FROM test_orders WHERE id = ?", 1).Error
+	}))
+	assert.ErrorIs(t, db.Transaction(func(tx *gorm.DB) error {
+		return failed
+	}), failed)
+
+	// Firebolt has no savepoints for nested transactions, they run in the outer one with DisableNestedTransaction
+	assert.ErrorIs(t, db.Transaction(func(tx *gorm.DB) error {
+		return tx.Transaction(func(tx *gorm.DB) error {
+			return nil
+		})
+	}), ErrSavePointsUnsupported)
+	assert.NoError(t, db.Session(&gorm.Session{DisableNestedTransaction: true}).Transaction(func(tx *gorm.DB) error {
+		return tx.Transaction(func(tx *gorm.DB) error {
+			return tx.Exec("DELETE FROM test_orders WHERE id = ?", 2).Error
+		})
+	}))
+
+	assert.Equal(t, []string{
+		"BEGIN", "DELETE FROM test_orders WHERE id = ?", "COMMIT",
+		"BEGIN", "ROLLBACK",
+		"BEGIN", "ROLLBACK",
+		"BEGIN", "DELETE FROM test_orders WHERE id = ?", "COMMIT",
+	}, fake.received())
+
+	_, err := db.Statement.ConnPool.(*sql.DB).BeginTx(db.Statement.Context, &sql.TxOptions{ReadOnly: true})
+	assert.EqualError(t, err, "read only transactions aren't supported")
+}
+
+func TestTransactionsSDK(t *testing.T) {
+	server := newFakeFirebolt(t, nil)
+
+	// the SDK sends every statement as a request of its own, which doesn't carry a transaction
+	_, err := gorm.Open(New(Config{DSN: server.dsn(), Transactions: true}), &gorm.Config{DisableAutomaticPing: true, Logger: logger.Discard})
+	assert.ErrorIs(t, err, ErrTransactionsUnsupported)
+
+	db := openSDKDB(t, Config{}, server)
+	assert.ErrorIs(t, db.Begin().Error, ErrTransactionsUnsupported)
+	assert.ErrorIs(t, db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&testOrder{ID: 1}).Error
+	}), ErrTransactionsUnsupported)
+	assert.NoError(t, db.Create(&testOrder{ID: 1}).Error)
+	assert.Len(t, server.received(), 1)
+}
@@ -0,0 +1,51 @@
+package firebolt
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UnnestExpr is UNNEST array join, it expands each row into a row per element of the arrays
+type UnnestExpr struct {
+	// Arrays are the array columns or expressions to expand, arrays of the same row are expanded in parallel
+	Arrays []clause.Column
+	// Aliases name the elements of the arrays
+	Aliases []string
+}
+
+func (unnest UnnestExpr) Build(builder clause.Builder) {
+	builder.WriteString("UNNEST(")
+	for idx, array := range unnest.Arrays {
+		if idx > 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteQuoted(array)
+		if idx < len(unnest.Aliases) && unnest.Aliases[idx] != "" {
+			builder.WriteString(" AS ")
+			builder.WriteQuoted(unnest.Aliases[idx])
+		}
+	}
+	builder.WriteByte(')')
+}
+
+// Unnest returns a scope joining the elements of the array column to each row under the alias, e.g.
+//
+//	db.Model(&Post{}).Scopes(firebolt.Unnest("tags", "tag")).Where("tag LIKE ?", "go%").Group("tag").Select("tag, count(*)")
+//
+// more column and alias pairs can be given to expand several arrays in parallel, a column without an alias is an error
+func Unnest(column, alias string, more ...string) func(*gorm.DB) *gorm.DB {
+	unnest := UnnestExpr{Arrays: []clause.Column{columnOf(column)}, Aliases: []string{alias}}
+	for i := 0; i+1 < len(more); i += 2 {
+		unnest.Arrays = append(unnest.Arrays, columnOf(more[i]))
+		unnest.Aliases = append(unnest.Aliases, more[i+1])
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		if len(more)%2 != 0 {
+			_ = db.AddError(fmt.Errorf("UNNEST array %s has no alias", more[len(more)-1]))
+			return db
+		}
+		return db.Joins("?", unnest)
+	}
+}
@@ -0,0 +1,54 @@
+package firebolt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testPost struct {
+	ID     int
+	Title  string
+	Tags   []string `gorm:"serializer:json"`
+	Scores []int    `gorm:"serializer:json"`
+}
+
+func TestUnnest(t *testing.T) {
+	db := dryRunDB(t)
+
+	type tagCount struct {
+		Tag   string
+		Count int
+	}
+	var counts []tagCount
+	stmt := db.Model(&testPost{}).Select("tag, count(*) AS count").
+		Scopes(Unnest("tags", "tag")).
+		Where("tag LIKE ?", "go%").Group("tag").Find(&counts).Statement
+	assert.Equal(t,
+		`SELECT tag, count(*) AS count FROM "test_posts" UNNEST("tags" AS "tag") WHERE tag LIKE ? GROUP BY "tag"`,
+		stmt.SQL.String())
+	assert.Equal(t, []interface{}{"go%"}, stmt.Vars)
+}
+
+func TestUnnestMultipleArrays(t *testing.T) {
+	db := dryRunDB(t)
+
+	stmt := db.Model(&testPost{}).Select("id, tag, score").
+		Scopes(Unnest("tags", "tag", "test_posts.scores", "score")).
+		Where("score > ?", 1).Find(&[]testPost{}).Statement
+	assert.Equal(t,
+		`SELECT id, tag, score FROM "test_posts" UNNEST("tags" AS "tag","test_posts"."scores" AS "score") WHERE score > ?`,
+		stmt.SQL.String())
+
+	var count int64
+	stmt = db.Model(&testPost{}).Scopes(Unnest("tags", "tag")).Where("tag = ?", "go").Count(&count).Statement
+	assert.Equal(t, `SELECT count(*) FROM "test_posts" UNNEST("tags" AS "tag") WHERE tag = ?`, stmt.SQL.String())
+}
+
+func TestUnnestMissingAlias(t *testing.T) {
+	db := dryRunDB(t)
+
+	tx := db.Model(&testPost{}).Scopes(Unnest("tags", "tag", "scores")).Find(&[]testPost{})
+	assert.EqualError(t, tx.Error, "UNNEST array scores has no alias")
+	assert.Empty(t, tx.Statement.SQL.String())
+}
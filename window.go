@@ -0,0 +1,155 @@
+package firebolt
+
+import (
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm/clause"
+)
+
+// WindowExpr is a window function call with OVER clause, it can be used as an argument of Select and Qualify,
+// and with OrderByExpr in ORDER BY, e.g.
+//
+//	db.Select("day, ?", firebolt.RunningSum("amount").OrderBy("day", false).As("total"))
+type WindowExpr struct {
+	// Func is the function call, e.g. ROW_NUMBER()
+	Func       clause.Expression
+	Partitions []clause.Column
+	Orders     []clause.OrderByColumn
+	Frame      *WindowFrame
+}
+
+// WindowFrame is the frame of a window, e.g. ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW
+type WindowFrame struct {
+	// Unit is either ROWS or RANGE
+	Unit  string
+	Start FrameBound
+	End   FrameBound
+}
+
+// FrameBound is a boundary of a window frame
+type FrameBound string
+
+const (
+	UnboundedPreceding FrameBound = "UNBOUNDED PRECEDING"
+	CurrentRow         FrameBound = "CURRENT ROW"
+	UnboundedFollowing FrameBound = "UNBOUNDED FOLLOWING"
+)
+
+// Preceding returns the frame boundary n rows (or values for RANGE) before the current row
+func Preceding(n int) FrameBound {
+	return FrameBound(strconv.Itoa(n) + " PRECEDING")
+}
+
+// Following returns the frame boundary n rows (or values for RANGE) after the current row
+func Following(n int) FrameBound {
+	return FrameBound(strconv.Itoa(n) + " FOLLOWING")
+}
+
+// RowNumber returns ROW_NUMBER() window function
+func RowNumber() WindowExpr {
+	return WindowExpr{Func: clause.Expr{SQL: "ROW_NUMBER()"}}
+}
+
+// Rank returns RANK() window function
+func Rank() WindowExpr {
+	return WindowExpr{Func: clause.Expr{SQL: "RANK()"}}
+}
+
+// DenseRank returns DENSE_RANK() window function
+func DenseRank() WindowExpr {
+	return WindowExpr{Func: clause.Expr{SQL: "DENSE_RANK()"}}
+}
+
+// Lag returns LAG() window function, the value of the column offset rows before the current row,
+// defaultValue is returned when there is no such row, it is omitted when nil
+func Lag(column string, offset int, defaultValue interface{}) WindowExpr {
+	return WindowExpr{Func: offsetFunc("LAG", column, offset, defaultValue)}
+}
+
+// Lead returns LEAD() window function, the value of the column offset rows after the current row,
+// defaultValue is returned when there is no such row, it is omitted when nil
+func Lead(column string, offset int, defaultValue interface{}) WindowExpr {
+	return WindowExpr{Func: offsetFunc("LEAD", column, offset, defaultValue)}
+}
+
+func offsetFunc(name, column string, offset int, defaultValue interface{}) clause.Expression {
+	if defaultValue == nil {
+		return clause.Expr{SQL: fmt.Sprintf("%s(?, %d)", name, offset), Vars: []interface{}{columnOf(column)}}
+	}
+	return clause.Expr{SQL: fmt.Sprintf("%s(?, %d, ?)", name, offset), Vars: []interface{}{columnOf(column), defaultValue}}
+}
+
+// Aggregate returns an aggregate function of the column used as a window function, e.g. Aggregate("AVG", "amount")
+func Aggregate(function, column string) WindowExpr {
+	return WindowExpr{Func: clause.Expr{SQL: function + "(?)", Vars: []interface{}{columnOf(column)}}}
+}
+
+// Sum returns SUM() of the column over the window
+func Sum(column string) WindowExpr {
+	return Aggregate("SUM", column)
+}
+
+// RunningSum returns SUM() of the column over all the rows from the start of the partition up to the current row
+func RunningSum(column string) WindowExpr {
+	return Sum(column).Rows(UnboundedPreceding, CurrentRow)
+}
+
+// PartitionBy adds columns to PARTITION BY of the window
+func (window WindowExpr) PartitionBy(columns ...string) WindowExpr {
+	partitions := make([]clause.Column, 0, len(window.Partitions)+len(columns))
+	window.Partitions = append(append(partitions, window.Partitions...), columnsOf(columns)...)
+	return window
+}
+
+// OrderBy adds a column to ORDER BY of the window
+func (window WindowExpr) OrderBy(column string, desc bool) WindowExpr {
+	orders := make([]clause.OrderByColumn, 0, len(window.Orders)+1)
+	window.Orders = append(append(orders, window.Orders...), clause.OrderByColumn{Column: columnOf(column), Desc: desc})
+	return window
+}
+
+// Rows sets ROWS BETWEEN start AND end frame of the window
+func (window WindowExpr) Rows(start, end FrameBound) WindowExpr {
+	window.Frame = &WindowFrame{Unit: "ROWS", Start: start, End: end}
+	return window
+}
+
+// Range sets RANGE BETWEEN start AND end frame of the window
+func (window WindowExpr) Range(start, end FrameBound) WindowExpr {
+	window.Frame = &WindowFrame{Unit: "RANGE", Start: start, End: end}
+	return window
+}
+
+// As names the window function result, to be used in Select
+func (window WindowExpr) As(alias string) clause.Expression {
+	return clause.Expr{SQL: "? AS ?", Vars: []interface{}{window, clause.Column{Name: alias}}}
+}
+
+func (window WindowExpr) Build(builder clause.Builder) {
+	window.Func.Build(builder)
+	builder.WriteString(" OVER (")
+	separator := ""
+	if len(window.Partitions) > 0 {
+		builder.WriteString("PARTITION BY ")
+		writeColumns(builder, window.Partitions)
+		separator = " "
+	}
+	if len(window.Orders) > 0 {
+		builder.WriteString(separator + "ORDER BY ")
+		clause.OrderBy{Columns: window.Orders}.Build(builder)
+		separator = " "
+	}
+	if window.Frame != nil {
+		builder.WriteString(fmt.Sprintf("%s%s BETWEEN %s AND %s", separator, window.Frame.Unit, window.Frame.Start, window.Frame.End))
+	}
+	builder.WriteByte(')')
+}
+
+// OrderByExpr returns ORDER BY clause sorting by the expression, e.g. by a window function
+func OrderByExpr(expr clause.Expression, desc bool) clause.OrderBy {
+	if desc {
+		return clause.OrderBy{Expression: clause.Expr{SQL: "? DESC", Vars: []interface{}{expr}}}
+	}
+	return clause.OrderBy{Expression: expr}
+}
@@ -0,0 +1,64 @@
+package firebolt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/clause"
+)
+
+func buildExpr(t *testing.T, expr clause.Expression) (string, []interface{}) {
+	stmt := dryRunDB(t).Statement
+	expr.Build(stmt)
+	return stmt.SQL.String(), stmt.Vars
+}
+
+func TestWindowFunctions(t *testing.T) {
+	tests := []struct {
+		expr clause.Expression
+		sql  string
+		vars []interface{}
+	}{
+		{RowNumber(), `ROW_NUMBER() OVER ()`, nil},
+		{Rank().OrderBy("score", true), `RANK() OVER (ORDER BY "score" DESC)`, nil},
+		{DenseRank().PartitionBy("team", "users.league").OrderBy("score", true).OrderBy("name", false),
+			`DENSE_RANK() OVER (PARTITION BY "team","users"."league" ORDER BY "score" DESC,"name")`, nil},
+		{Lag("amount", 1, nil).PartitionBy("user_id").OrderBy("day", false),
+			`LAG("amount", 1) OVER (PARTITION BY "user_id" ORDER BY "day")`, nil},
+		{Lead("amount", 2, 0).OrderBy("day", false), `LEAD("amount", 2, ?) OVER (ORDER BY "day")`, []interface{}{0}},
+		{RunningSum("amount").PartitionBy("user_id").OrderBy("day", false),
+			`SUM("amount") OVER (PARTITION BY "user_id" ORDER BY "day" ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW)`, nil},
+		{Aggregate("AVG", "amount").OrderBy("day", false).Rows(Preceding(6), CurrentRow),
+			`AVG("amount") OVER (ORDER BY "day" ROWS BETWEEN 6 PRECEDING AND CURRENT ROW)`, nil},
+		{Sum("amount * price").PartitionBy("region").Range(UnboundedPreceding, UnboundedFollowing),
+			`SUM(amount * price) OVER (PARTITION BY "region" RANGE BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING)`, nil},
+		{Lag("amount", 1, nil).OrderBy("day", false).Rows(Preceding(1), Following(1)).As("previous"),
+			`LAG("amount", 1) OVER (ORDER BY "day" ROWS BETWEEN 1 PRECEDING AND 1 FOLLOWING) AS "previous"`, nil},
+	}
+
+	for _, test := range tests {
+		sql, vars := buildExpr(t, test.expr)
+		assert.Equal(t, test.sql, sql)
+		assert.Equal(t, test.vars, vars)
+	}
+}
+
+func TestWindowInQuery(t *testing.T) {
+	db := dryRunDB(t)
+
+	rank := Rank().PartitionBy("customer").OrderBy("amount", true)
+	stmt := db.Model(&testOrder{}).
+		Select("customer, amount, ?, ?", rank.As("rank"), Lead("amount", 1, -1).PartitionBy("customer").OrderBy("id", false)).
+		Where("amount > ?", 5).
+		Clauses(Qualify("? <= ?", rank, 3), OrderByExpr(rank, true)).
+		Find(&[]testOrder{}).Statement
+
+	assert.Equal(t, strings.Join([]string{
+		`SELECT customer, amount, RANK() OVER (PARTITION BY "customer" ORDER BY "amount" DESC) AS "rank", LEAD("amount", 1, ?) OVER (PARTITION BY "customer" ORDER BY "id")`,
+		`FROM "test_orders" WHERE amount > ?`,
+		`QUALIFY RANK() OVER (PARTITION BY "customer" ORDER BY "amount" DESC) <= ?`,
+		`ORDER BY RANK() OVER (PARTITION BY "customer" ORDER BY "amount" DESC) DESC`,
+	}, " "), stmt.SQL.String())
+	assert.Equal(t, []interface{}{-1, 5, 3}, stmt.Vars)
+}
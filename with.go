@@ -0,0 +1,62 @@
+package firebolt
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CTE is a common table expression of WITH clause
+type CTE struct {
+	Name string
+	// Columns optionally renames the columns returned by the query
+	Columns []string
+	Query   *gorm.DB
+}
+
+// WithClause is WITH clause, it prefixes the query with common table expressions
+type WithClause struct {
+	CTEs []CTE
+}
+
+// With returns WITH clause defining a common table expression, the query can reference CTEs defined before it.
+// Multiple CTEs are added to the same clause, e.g.
+//
+//	db.Clauses(firebolt.With("recent", recentQuery), firebolt.With("top", db.Table("recent").Order("amount DESC").Limit(10))).
+//		Table("top").Find(&rows)
+func With(name string, query *gorm.DB, columns ...string) WithClause {
+	return WithClause{CTEs: []CTE{{Name: name, Columns: columns, Query: query}}}
+}
+
+func (with WithClause) Name() string {
+	return ClauseWith
+}
+
+func (with WithClause) Build(builder clause.Builder) {
+	for idx, cte := range with.CTEs {
+		if idx > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteQuoted(cte.Name)
+		if len(cte.Columns) > 0 {
+			builder.WriteString(" (")
+			for idx, column := range cte.Columns {
+				if idx > 0 {
+					builder.WriteByte(',')
+				}
+				builder.WriteQuoted(column)
+			}
+			builder.WriteByte(')')
+		}
+		builder.WriteString(" AS (")
+		builder.AddVar(builder, cte.Query)
+		builder.WriteByte(')')
+	}
+}
+
+func (with WithClause) MergeClause(c *clause.Clause) {
+	if existing, ok := c.Expression.(WithClause); ok {
+		ctes := make([]CTE, 0, len(existing.CTEs)+len(with.CTEs))
+		with.CTEs = append(append(ctes, existing.CTEs...), with.CTEs...)
+	}
+	c.Expression = with
+}
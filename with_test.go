@@ -0,0 +1,60 @@
+package firebolt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type testOrder struct {
+	ID       int
+	Customer string
+	Amount   float64
+}
+
+func TestWith(t *testing.T) {
+	db := dryRunDB(t)
+
+	recent := db.Model(&testOrder{}).Where("amount > ?", 100)
+	var orders []testOrder
+	stmt := db.Clauses(With("recent", recent)).Table("recent").Where("customer = ?", "bob").Find(&orders).Statement
+	assert.Equal(t,
+		`WITH "recent" AS (SELECT * FROM "test_orders" WHERE amount > ?) SELECT * FROM "recent" WHERE customer = ?`,
+		stmt.SQL.String())
+	assert.Equal(t, []interface{}{100, "bob"}, stmt.Vars)
+}
+
+func TestWithMultiple(t *testing.T) {
+	db := dryRunDB(t)
+
+	totals := db.Model(&testOrder{}).Select("customer, sum(amount)").Where("amount > ?", 0).Group("customer")
+	top := db.Table("totals").Where("total > ?", 1000)
+
+	var customers []string
+	stmt := db.Clauses(With("totals", totals, "customer", "total"), With("top", top)).
+		Table("top").Pluck("customer", &customers).Statement
+	assert.Equal(t,
+		`WITH "totals" ("customer","total") AS (SELECT customer, sum(amount) FROM "test_orders" WHERE amount > ? GROUP BY "customer"), "top" AS (SELECT * FROM "totals" WHERE total > ?) SELECT "customer" FROM "top"`,
+		stmt.SQL.String())
+	assert.Equal(t, []interface{}{0, 1000}, stmt.Vars)
+}
+
+func TestWithCount(t *testing.T) {
+	db := dryRunDB(t)
+
+	var count int64
+	stmt := db.Clauses(With("big", db.Model(&testOrder{}).Where("amount > ?", 100))).Table("big").Count(&count).Statement
+	assert.Equal(t, `WITH "big" AS (SELECT * FROM "test_orders" WHERE amount > ?) SELECT count(*) FROM "big"`, stmt.SQL.String())
+}
+
+func TestWithInvalidCTEs(t *testing.T) {
+	db := dryRunDB(t)
+
+	assert.Error(t, db.Clauses(WithClause{}).Find(&[]testOrder{}).Error)
+	assert.Error(t, db.Clauses(With("", db.Table("test_orders"))).Find(&[]testOrder{}).Error)
+	assert.Error(t, db.Clauses(With("empty", nil)).Find(&[]testOrder{}).Error)
+
+	stmt := db.Session(&gorm.Session{}).Find(&[]testOrder{}).Statement
+	assert.Equal(t, `SELECT * FROM "test_orders"`, stmt.SQL.String())
+}